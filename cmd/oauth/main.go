@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/tsub/serverless-daily-standup-bot/internal/oauth"
+)
+
+// Response is of type APIGatewayProxyResponse since we're leveraging the
+// AWS Lambda Proxy Request functionality (default behavior)
+//
+// https://serverless.com/framework/docs/providers/aws/events/apigateway/#lambda-proxy-integration
+type Response events.APIGatewayProxyResponse
+
+// Handler is our lambda handler invoked by the `lambda.Start` function call
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (Response, error) {
+	if strings.HasSuffix(request.Path, "/install") {
+		return Response{
+			StatusCode: 302,
+			Headers: map[string]string{
+				"Location": oauth.InstallURL(time.Now()),
+			},
+		}, nil
+	}
+
+	query := url.Values{}
+	for k, v := range request.QueryStringParameters {
+		query.Set(k, v)
+	}
+
+	status, body, err := oauth.Redirect(ctx, query)
+
+	return Response{
+		StatusCode: status,
+		Body:       body,
+		Headers: map[string]string{
+			"Content-Type": "text/html; charset=utf-8",
+		},
+	}, err
+}
+
+func main() {
+	lambda.Start(Handler)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/interactive"
+	"github.com/tsub/serverless-daily-standup-bot/internal/job"
+	"github.com/tsub/serverless-daily-standup-bot/internal/oauth"
+	"github.com/tsub/serverless-daily-standup-bot/internal/questions"
+	"github.com/tsub/serverless-daily-standup-bot/internal/schedule"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slash"
+	"github.com/tsub/serverless-daily-standup-bot/internal/socketmode"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/start"
+	"github.com/tsub/serverless-daily-standup-bot/internal/webhook"
+)
+
+var port = os.Getenv("PORT")
+
+// appToken enables Socket Mode, for workspaces which can't reach our HTTP
+// endpoints.
+var appToken = os.Getenv("SLACK_APP_TOKEN")
+
+// acceptFunc is the shape of the handlers answering Slack with a body.
+type acceptFunc func(ctx context.Context, q job.Queue, body []byte) (int, string, error)
+
+func accept(q job.Queue, f acceptFunc, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		status, respBody, err := f(r.Context(), q, body)
+		if err != nil {
+			log.Printf("%s: %s", r.URL.Path, err)
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		w.Write([]byte(respBody))
+	}
+}
+
+// skipRetries answers right away the events Slack resends after a slow
+// response, which have been queued already.
+func skipRetries(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if webhook.Retried(r.Header.Get("X-Slack-Retry-Num"), r.Header.Get("X-Slack-Retry-Reason")) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+func handleSlash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	status, respBody, err := slash.Handle(r.Context(), body)
+	if err != nil {
+		log.Printf("%s: %s", r.URL.Path, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(respBody))
+}
+
+// dispatch hands Socket Mode envelopes to the same handlers as the HTTP
+// routes, in the shape they receive over HTTP.
+func dispatch(q job.Queue) socketmode.Handler {
+	return func(ctx context.Context, envelopeType string, payload json.RawMessage) (json.RawMessage, error) {
+		switch envelopeType {
+		case socketmode.TypeEventsAPI:
+			_, _, err := webhook.Accept(ctx, q, payload)
+			return nil, err
+		case socketmode.TypeSlashCommands:
+			var fields map[string]interface{}
+			if err := json.Unmarshal(payload, &fields); err != nil {
+				return nil, err
+			}
+
+			form := url.Values{}
+			for k, v := range fields {
+				form.Set(k, fmt.Sprint(v))
+			}
+
+			_, body, err := slash.Handle(ctx, []byte(form.Encode()))
+			if body == "" {
+				return nil, err
+			}
+			return json.RawMessage(body), err
+		case socketmode.TypeInteractive:
+			form := url.Values{"payload": {string(payload)}}
+
+			_, body, err := interactive.Accept(ctx, q, []byte(form.Encode()))
+			if body == "" {
+				return nil, err
+			}
+			return json.RawMessage(body), err
+		default:
+			log.Printf("unsupported envelope type: %s", envelopeType)
+			return nil, nil
+		}
+	}
+}
+
+func main() {
+	if port == "" {
+		port = "3000"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := dynamo.New(session.New())
+	q := job.NewMemory(ctx, 4)
+
+	// Stand in for DynamoDB Streams: every standup written by this process
+	// is processed like the send_questions function does with a record.
+	standup.Subscribe(func(s standup.Standup) {
+		j, err := job.New(questions.JobType, questions.Key{TeamID: s.TeamID, UserID: s.UserID, Date: s.Date})
+		if err != nil {
+			log.Printf("failed to create job: %s", err)
+			return
+		}
+		// Processing the same standup concurrently would post its messages
+		// twice, and races the answers of the member
+		j.Key = job.UserKey(s.TeamID, s.UserID)
+
+		// Don't block the writer, which may be a worker of the same queue
+		go func() {
+			if err := q.Enqueue(ctx, j); err != nil {
+				log.Printf("failed to enqueue job: %s", err)
+			}
+		}()
+	})
+
+	local := schedule.NewLocal(db)
+	schedule.Use(local)
+	go local.Run(ctx, start.Run)
+
+	if appToken != "" {
+		go func() {
+			if err := socketmode.New(appToken, dispatch(q)).Run(ctx); err != nil && err != context.Canceled {
+				log.Printf("socket mode stopped: %s", err)
+			}
+		}()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", skipRetries(accept(q, webhook.Accept, "text/plain")))
+	mux.HandleFunc("/interactive", accept(q, interactive.Accept, "application/json"))
+	mux.HandleFunc("/slash", handleSlash)
+	mux.HandleFunc("/oauth/install", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, oauth.InstallURL(time.Now()), http.StatusFound)
+	})
+	mux.HandleFunc("/oauth/redirect", func(w http.ResponseWriter, r *http.Request) {
+		status, body, err := oauth.Redirect(r.Context(), r.URL.Query())
+		if err != nil {
+			log.Printf("%s: %s", r.URL.Path, err)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	srv := &http.Server{Addr: ":" + port, Handler: mux}
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+
+		log.Println("shutting down")
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer shutdownCancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("failed to shut down: %s", err)
+		}
+		cancel()
+	}()
+
+	log.Printf("listening on :%s", port)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
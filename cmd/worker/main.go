@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/tsub/serverless-daily-standup-bot/internal/job"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackhttp"
+
+	// Register the jobs enqueued by the handlers
+	_ "github.com/tsub/serverless-daily-standup-bot/internal/interactive"
+	_ "github.com/tsub/serverless-daily-standup-bot/internal/webhook"
+)
+
+// Handler is our lambda handler invoked by the `lambda.Start` function call
+func Handler(ctx context.Context, e events.SQSEvent) error {
+	defer slackhttp.LogStats()
+
+	for _, record := range e.Records {
+		j, err := job.Decode(record.Body)
+		if err != nil {
+			// A malformed message will never succeed, so don't retry it
+			log.Printf("invalid job %s: %s", record.MessageId, err)
+			continue
+		}
+
+		log.Printf("run job %s: %s", record.MessageId, j.Type)
+
+		if err := job.Run(ctx, j); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	lambda.Start(Handler)
+}
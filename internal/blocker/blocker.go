@@ -0,0 +1,198 @@
+package blocker
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/guregu/dynamo"
+)
+
+var blockersTable = os.Getenv("BLOCKERS_TABLE")
+
+type Status string
+
+const (
+	StatusOpen     Status = "open"
+	StatusResolved Status = "resolved"
+)
+
+// Blocker is a blocker reported by a member, tracked across the standups
+// of the member until it is resolved.
+type Blocker struct {
+	TargetChannelID string   `dynamo:"target_channel_id"`
+	ID              string   `dynamo:"id"`
+	UserID          string   `dynamo:"user_id"`
+	Text            string   `dynamo:"text"`
+	FirstSeen       string   `dynamo:"first_seen"`
+	Standups        []string `dynamo:"standups,set"`
+	Status          Status   `dynamo:"status"`
+	ResolvedBy      string   `dynamo:"resolved_by"`
+	ResolvedAt      string   `dynamo:"resolved_at"`
+}
+
+var now = time.Now
+
+// Open returns the open blockers of the members of a setting.
+func Open(db *dynamo.DB, targetChannelID string) ([]Blocker, error) {
+	table := db.Table(blockersTable)
+
+	var blockers []Blocker
+	err := table.Get("target_channel_id", targetChannelID).
+		Filter("$ = ?", "status", StatusOpen).
+		Consistent(true).
+		All(&blockers)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockers, nil
+}
+
+// Of returns the blockers of a member, the first seen first.
+func Of(db *dynamo.DB, targetChannelID string, userID string) ([]Blocker, error) {
+	table := db.Table(blockersTable)
+
+	var blockers []Blocker
+	err := table.Get("target_channel_id", targetChannelID).
+		Range("id", dynamo.BeginsWith, userID+"/").
+		Consistent(true).
+		All(&blockers)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockers, nil
+}
+
+// OpenOf returns the open blocker of a member, or dynamo.ErrNotFound.
+func OpenOf(db *dynamo.DB, targetChannelID string, userID string) (*Blocker, error) {
+	blockers, err := Of(db, targetChannelID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return latestOpen(blockers)
+}
+
+func latestOpen(blockers []Blocker) (*Blocker, error) {
+	for i := len(blockers) - 1; i >= 0; i-- {
+		if blockers[i].Status == StatusOpen {
+			return &blockers[i], nil
+		}
+	}
+
+	return nil, dynamo.ErrNotFound
+}
+
+// Report records the blocker a member answered in the standup of a date,
+// which updates the open blocker of the member if there is one. A blocker
+// resolved since the standup was answered stays resolved.
+func Report(db *dynamo.DB, targetChannelID string, userID string, date string, text string) (*Blocker, error) {
+	blockers, err := Of(db, targetChannelID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := latestOpen(blockers)
+	if err == nil {
+		return b.update(db.Table(blockersTable).Update("target_channel_id", b.TargetChannelID).
+			Range("id", b.ID).
+			Set("text", text).
+			AddStringsToSet("standups", date))
+	}
+
+	for i := range blockers {
+		if contains(blockers[i].Standups, date) {
+			return &blockers[i], nil
+		}
+	}
+
+	b = &Blocker{
+		TargetChannelID: targetChannelID,
+		ID:              userID + "/" + date,
+		UserID:          userID,
+		Text:            text,
+		FirstSeen:       date,
+		Standups:        []string{date},
+		Status:          StatusOpen,
+	}
+
+	if err := db.Table(blockersTable).Put(b).Run(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Continue records that the blocker still blocks its member in the standup
+// of a date.
+func (b *Blocker) Continue(db *dynamo.DB, date string) error {
+	_, err := b.update(db.Table(blockersTable).Update("target_channel_id", b.TargetChannelID).
+		Range("id", b.ID).
+		AddStringsToSet("standups", date))
+
+	return err
+}
+
+// Resolve closes the blocker, recording who resolved it.
+func (b *Blocker) Resolve(db *dynamo.DB, userID string) error {
+	_, err := b.update(db.Table(blockersTable).Update("target_channel_id", b.TargetChannelID).
+		Range("id", b.ID).
+		Set("status", StatusResolved).
+		Set("resolved_by", userID).
+		Set("resolved_at", now().UTC().Format(time.RFC3339)))
+
+	return err
+}
+
+func (b *Blocker) update(u *dynamo.Update) (*Blocker, error) {
+	if err := u.If("$ = ?", "status", StatusOpen).Value(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Question is asked instead of the blocker question while the blocker is
+// open.
+func (b *Blocker) Question() string {
+	return fmt.Sprintf("Is \"%s\" still blocking you? Answer yes, no, or what blocks you now.", b.Text)
+}
+
+// Still reports whether an answer to Question means the blocker is still
+// there, unchanged.
+func Still(answer string) bool {
+	switch strings.ToLower(strings.Trim(strings.TrimSpace(answer), ".!")) {
+	case "yes", "y", "yep", "still", "still blocked":
+		return true
+	default:
+		return false
+	}
+}
+
+// Age returns for how many days the blocker has been open.
+func (b *Blocker) Age() int {
+	first, err := time.Parse("2006-01-02", b.FirstSeen)
+	if err != nil {
+		return 0
+	}
+
+	days := int(now().UTC().Sub(first).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+
+	return days
+}
+
+func contains(vs []string, v string) bool {
+	for _, s := range vs {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,24 @@
+package blocker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStill(t *testing.T) {
+	for answer, want := range map[string]bool{"Yes": true, " still. ": true, "no": false, "Waiting on the API keys": false} {
+		if got := Still(answer); got != want {
+			t.Fatalf("%q: want %t, got %t", answer, want, got)
+		}
+	}
+}
+
+func TestAge(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2018, 9, 6, 9, 0, 0, 0, time.UTC) }
+
+	b := &Blocker{FirstSeen: "2018-09-03"}
+	if got := b.Age(); got != 3 {
+		t.Fatalf("Want 3 days, got %d", got)
+	}
+}
@@ -0,0 +1,150 @@
+package installation
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+)
+
+var installationsTable = os.Getenv("INSTALLATIONS_TABLE")
+
+// Tokens of a single-workspace deployment, used when there is no table of
+// installations or for records created before they had a team_id.
+var slackToken = os.Getenv("SLACK_TOKEN")
+var botSlackToken = os.Getenv("SLACK_BOT_TOKEN")
+
+// Installations are cached briefly so that a revoked token stops being
+// used soon after the table is updated.
+var cacheTTL = 5 * time.Minute
+
+type Installation struct {
+	TeamID      string `dynamo:"team_id"`
+	TeamName    string `dynamo:"team_name"`
+	AppID       string `dynamo:"app_id"`
+	BotUserID   string `dynamo:"bot_user_id"`
+	BotToken    string `dynamo:"bot_token"`
+	Scope       string `dynamo:"scope"`
+	UserID      string `dynamo:"user_id"`
+	UserToken   string `dynamo:"user_token"`
+	UserScope   string `dynamo:"user_scope"`
+	InstalledAt string `dynamo:"installed_at"`
+}
+
+type entry struct {
+	installation *Installation
+	expires      time.Time
+}
+
+var (
+	mu    sync.Mutex
+	cache = map[string]entry{}
+)
+
+func Get(db *dynamo.DB, teamID string) (*Installation, error) {
+	mu.Lock()
+	e, ok := cache[teamID]
+	mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		if e.installation == nil {
+			return nil, dynamo.ErrNotFound
+		}
+		return e.installation, nil
+	}
+
+	table := db.Table(installationsTable)
+
+	var i Installation
+	err := table.Get("team_id", teamID).One(&i)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+
+	e = entry{expires: time.Now().Add(cacheTTL)}
+	if err == nil {
+		e.installation = &i
+	}
+
+	mu.Lock()
+	cache[teamID] = e
+	mu.Unlock()
+
+	if e.installation == nil {
+		return nil, dynamo.ErrNotFound
+	}
+
+	return e.installation, nil
+}
+
+func Save(db *dynamo.DB, i Installation) error {
+	table := db.Table(installationsTable)
+
+	if err := table.Put(i).Run(); err != nil {
+		return err
+	}
+
+	forget(i.TeamID)
+
+	return nil
+}
+
+// Delete removes the installation of a team, e.g. once the app has been
+// uninstalled.
+func Delete(db *dynamo.DB, teamID string) error {
+	table := db.Table(installationsTable)
+
+	if err := table.Delete("team_id", teamID).Run(); err != nil {
+		return err
+	}
+
+	forget(teamID)
+
+	return nil
+}
+
+func forget(teamID string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(cache, teamID)
+}
+
+// Clients returns the Slack clients acting as the bot and as the installing
+// user of a team.
+func Clients(db *dynamo.DB, teamID string) (bot slackapi.Client, user slackapi.Client, err error) {
+	botToken, userToken, err := tokens(db, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return slackapi.New(botToken), slackapi.New(userToken), nil
+}
+
+// tokens reads the tokens of a team from its installation, falling back to
+// the bot token for installations without a user token. Records without a
+// team_id, from before installations, use the environment. A team which
+// hasn't installed the app never gets the tokens of another.
+func tokens(db *dynamo.DB, teamID string) (string, string, error) {
+	botToken, userToken := botSlackToken, slackToken
+
+	if teamID != "" && installationsTable != "" {
+		i, err := Get(db, teamID)
+		if err == dynamo.ErrNotFound {
+			return "", "", fmt.Errorf("team %q hasn't installed the app", teamID)
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		botToken, userToken = i.BotToken, i.UserToken
+	}
+
+	if userToken == "" {
+		userToken = botToken
+	}
+
+	return botToken, userToken, nil
+}
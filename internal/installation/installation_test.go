@@ -0,0 +1,100 @@
+package installation
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/guregu/dynamo"
+)
+
+type mockedDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	Items map[string]map[string]*dynamodb.AttributeValue
+	Gets  int
+}
+
+func (m *mockedDynamo) GetItemWithContext(context aws.Context, input *dynamodb.GetItemInput, options ...request.Option) (*dynamodb.GetItemOutput, error) {
+	m.Gets++
+	return &dynamodb.GetItemOutput{Item: m.Items[*input.Key["team_id"].S]}, nil
+}
+
+func (m *mockedDynamo) PutItemWithContext(context aws.Context, input *dynamodb.PutItemInput, options ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.Items[*input.Item["team_id"].S] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func setup() (*mockedDynamo, *dynamo.DB) {
+	installationsTable = "installations"
+	botSlackToken = "xoxb-env"
+	slackToken = "xoxp-env"
+	cache = map[string]entry{}
+
+	mockedClient := &mockedDynamo{Items: map[string]map[string]*dynamodb.AttributeValue{}}
+	return mockedClient, dynamo.NewFromIface(mockedClient)
+}
+
+func TestTokensOfInstalledTeam(t *testing.T) {
+	mockedClient, db := setup()
+
+	if err := Save(db, Installation{TeamID: "T1", BotToken: "xoxb-t1"}); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		bot, user, err := tokens(db, "T1")
+		if err != nil {
+			t.Fatalf("%q", err)
+		}
+
+		// Without user scopes the bot token is used for both
+		if bot != "xoxb-t1" || user != "xoxb-t1" {
+			t.Fatalf("Unexpected tokens: %q, %q", bot, user)
+		}
+	}
+
+	if mockedClient.Gets != 1 {
+		t.Fatalf("Want 1 read of the table, got %d", mockedClient.Gets)
+	}
+}
+
+func TestTokensOfUninstalledTeam(t *testing.T) {
+	_, db := setup()
+
+	if _, _, err := tokens(db, "T2"); err == nil {
+		t.Fatal("Want an error for a team without an installation")
+	}
+}
+
+func TestTokensOfUserInstallation(t *testing.T) {
+	_, db := setup()
+
+	if err := Save(db, Installation{TeamID: "T1", BotToken: "xoxb-t1", UserToken: "xoxp-t1"}); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	bot, user, err := tokens(db, "T1")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if bot != "xoxb-t1" || user != "xoxp-t1" {
+		t.Fatalf("Unexpected tokens: %q, %q", bot, user)
+	}
+}
+
+func TestTokensWithoutTeamFromEnvironment(t *testing.T) {
+	_, db := setup()
+
+	// Standups and settings from before installations have no team_id
+	bot, user, err := tokens(db, "")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if bot != "xoxb-env" || user != "xoxp-env" {
+		t.Fatalf("Unexpected tokens: %q, %q", bot, user)
+	}
+}
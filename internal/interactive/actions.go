@@ -0,0 +1,257 @@
+package interactive
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/blocker"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/job"
+	"github.com/tsub/serverless-daily-standup-bot/internal/questions"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/summary"
+)
+
+// ActionJobType is the job handling a clicked button.
+const ActionJobType = "interactive.action"
+
+// actionHandler handles one action of a block_actions payload.
+type actionHandler func(ctx context.Context, db *dynamo.DB, botcl slackapi.Client, cl slackapi.Client, payload slackapi.InteractionCallback, action slackapi.Action) error
+
+var actionHandlers = map[string]actionHandler{
+	summary.ShowMoreActionID:          showMore,
+	summary.EscalationActionID:        resolveBlocker,
+	questions.SameAsPlannedActionID:   sameAsPlanned,
+	questions.SameAsYesterdayActionID: sameAsYesterday,
+}
+
+func init() {
+	job.Register(ActionJobType, func(ctx context.Context, payload json.RawMessage) error {
+		var callback slackapi.InteractionCallback
+		if err := json.Unmarshal(payload, &callback); err != nil {
+			return err
+		}
+
+		db := dynamo.New(session.New())
+
+		botcl, cl, err := installation.Clients(db, callback.Team.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, action := range callback.Actions {
+			h, ok := actionHandlers[action.ActionID]
+			if !ok {
+				continue
+			}
+
+			if err := h(ctx, db, botcl, cl, callback, action); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// actionKey is the key of the member whose standup the actions change,
+// rather than of whoever clicked, e.g. to resolve a blocker.
+func actionKey(payload slackapi.InteractionCallback) string {
+	for _, action := range payload.Actions {
+		if userID, _, _, err := summary.ParseActionValue(action.Value); err == nil {
+			return job.UserKey(payload.Team.ID, userID)
+		}
+	}
+
+	return job.UserKey(payload.Team.ID, payload.User.ID)
+}
+
+// acceptActions enqueues the block_actions payloads with a known action.
+func acceptActions(ctx context.Context, q job.Queue, payload slackapi.InteractionCallback) (int, string, error) {
+	known := false
+	for _, action := range payload.Actions {
+		if _, ok := actionHandlers[action.ActionID]; ok {
+			known = true
+		}
+	}
+	if !known {
+		return 200, "", nil
+	}
+
+	j, err := job.New(ActionJobType, payload)
+	if err != nil {
+		return 500, "", err
+	}
+	j.Key = actionKey(payload)
+
+	if err := q.Enqueue(ctx, j); err != nil {
+		return 500, "", err
+	}
+
+	return 200, "", nil
+}
+
+// showMore sends the whole of a truncated answer to the user who clicked.
+func showMore(ctx context.Context, db *dynamo.DB, botcl slackapi.Client, cl slackapi.Client, payload slackapi.InteractionCallback, action slackapi.Action) error {
+	userID, date, index, err := summary.ParseActionValue(action.Value)
+	if err != nil {
+		return err
+	}
+
+	s, err := standup.Get(db, userID, date, false)
+	if err == dynamo.ErrNotFound {
+		log.Printf("no stand-up of %s on %s", userID, date)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if index >= len(s.Questions) || index >= len(s.Answers) {
+		return nil
+	}
+
+	text := "*" + s.Questions[index].Text + "*\n" + s.Answers[index].FullText()
+
+	return slackapi.Respond(ctx, payload.ResponseURL, slackapi.Message{Text: text})
+}
+
+// resolveBlocker records who resolved an escalated blocker, and updates the
+// escalation message.
+func resolveBlocker(ctx context.Context, db *dynamo.DB, botcl slackapi.Client, cl slackapi.Client, payload slackapi.InteractionCallback, action slackapi.Action) error {
+	userID, date, _, err := summary.ParseActionValue(action.Value)
+	if err != nil {
+		return err
+	}
+
+	s, err := standup.Get(db, userID, date, true)
+	if err == dynamo.ErrNotFound {
+		log.Printf("no stand-up of %s on %s", userID, date)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.Escalation == nil {
+		return nil
+	}
+
+	b, err := blocker.OpenOf(db, s.TargetChannelID, s.UserID)
+	switch {
+	case err == nil:
+		if err := b.Resolve(db, payload.User.ID); err != nil {
+			return err
+		}
+	case err != dynamo.ErrNotFound:
+		return err
+	}
+
+	if err := s.Resolve(db, payload.User.ID); err != nil {
+		return err
+	}
+
+	return questions.UpdateEscalation(ctx, db, botcl, cl, s)
+}
+
+// sameAsPlanned answers a question with the plan of the previous standup.
+func sameAsPlanned(ctx context.Context, db *dynamo.DB, botcl slackapi.Client, cl slackapi.Client, payload slackapi.InteractionCallback, action slackapi.Action) error {
+	s, index, err := currentQuestion(db, payload, action)
+	if s == nil || err != nil {
+		return err
+	}
+
+	st, err := setting.Get(db, s.TargetChannelID)
+	if err != nil {
+		return err
+	}
+
+	plan, recap, ok := st.CarryOver()
+	if !ok || recap != index {
+		return nil
+	}
+
+	previous, err := s.Previous(db)
+	if err != nil {
+		return err
+	}
+
+	planned := standup.LastAnswerAt(previous, plan)
+	if planned == "" {
+		return nil
+	}
+
+	return answer(ctx, db, botcl, payload, action, s, index, planned, "Same as planned")
+}
+
+// sameAsYesterday answers a question with the previous answer to it.
+func sameAsYesterday(ctx context.Context, db *dynamo.DB, botcl slackapi.Client, cl slackapi.Client, payload slackapi.InteractionCallback, action slackapi.Action) error {
+	s, index, err := currentQuestion(db, payload, action)
+	if s == nil || err != nil {
+		return err
+	}
+
+	previous, err := s.Previous(db)
+	if err != nil {
+		return err
+	}
+
+	text := standup.LastAnswerTo(previous, s.Questions[index].Text)
+	if text == "" {
+		return nil
+	}
+
+	return answer(ctx, db, botcl, payload, action, s, index, text, "Same as yesterday")
+}
+
+// currentQuestion returns the standup of a button on a question, or nil if
+// the question isn't the one waiting for an answer anymore.
+func currentQuestion(db *dynamo.DB, payload slackapi.InteractionCallback, action slackapi.Action) (*standup.Standup, int, error) {
+	userID, date, index, err := summary.ParseActionValue(action.Value)
+	if err != nil {
+		return nil, 0, err
+	}
+	if userID != payload.User.ID {
+		return nil, 0, nil
+	}
+
+	s, err := standup.Get(db, userID, date, true)
+	if err == dynamo.ErrNotFound {
+		log.Printf("no stand-up of %s on %s", userID, date)
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !s.Open() || s.NextQuestion() != index || index >= len(s.Questions) {
+		// Answered in the meantime
+		return nil, 0, nil
+	}
+
+	return s, index, nil
+}
+
+// answer records the answer of a button and takes the buttons off the
+// question.
+func answer(ctx context.Context, db *dynamo.DB, botcl slackapi.Client, payload slackapi.InteractionCallback, action slackapi.Action, s *standup.Standup, index int, text string, label string) error {
+	if err := s.AppendAnswer(db, standup.Answer{Text: text, Raw: text, PostedAt: action.ActionTimestamp}); err != nil {
+		return err
+	}
+
+	question := questions.Title(s, index)
+	blocks, err := json.Marshal([]interface{}{
+		slackapi.Section(question),
+		slackapi.Context(slackapi.Markdown(":white_check_mark: " + label + ":\n" + text)),
+	})
+	if err != nil {
+		return err
+	}
+
+	return botcl.UpdateMessage(ctx, payload.Channel.ID, s.Questions[index].PostedAt, slackapi.Message{Text: question, Blocks: json.RawMessage(blocks)})
+}
@@ -0,0 +1,191 @@
+package interactive
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/questions"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/summary"
+)
+
+// mockedDynamo serves a standup, the standups before it and the setting of
+// their channel, and records the standup saved.
+type mockedDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	Standup  standup.Standup
+	Previous []standup.Standup
+	Setting  setting.Setting
+	Saved    *standup.Standup
+}
+
+func (m *mockedDynamo) GetItemWithContext(context aws.Context, input *dynamodb.GetItemInput, options ...request.Option) (*dynamodb.GetItemOutput, error) {
+	var item map[string]*dynamodb.AttributeValue
+	var err error
+	if _, ok := input.Key["target_channel_id"]; ok {
+		item, err = dynamo.MarshalItem(m.Setting)
+	} else {
+		item, err = dynamo.MarshalItem(m.Standup)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (m *mockedDynamo) QueryWithContext(context aws.Context, input *dynamodb.QueryInput, options ...request.Option) (*dynamodb.QueryOutput, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, s := range m.Previous {
+		item, err := dynamo.MarshalItem(s)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return &dynamodb.QueryOutput{Items: items, Count: aws.Int64(int64(len(items)))}, nil
+}
+
+func (m *mockedDynamo) PutItemWithContext(context aws.Context, input *dynamodb.PutItemInput, options ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.Saved = &standup.Standup{}
+	if err := dynamo.UnmarshalItem(input.Item, m.Saved); err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func newMockedDynamo() *mockedDynamo {
+	return &mockedDynamo{
+		Standup: standup.Standup{
+			UserID:          "U1",
+			Date:            "2019-01-02",
+			TargetChannelID: "C1",
+			Status:          standup.StatusAsking,
+			Questions: []standup.Question{
+				standup.Question{Text: "What did you do yesterday?", PostedAt: "1.0"},
+				standup.Question{Text: "What will you do today?"},
+			},
+		},
+		Previous: []standup.Standup{
+			standup.Standup{
+				UserID: "U1",
+				Date:   "2019-01-01",
+				Questions: []standup.Question{
+					standup.Question{Text: "What did you do yesterday?"},
+					standup.Question{Text: "What will you do today?"},
+				},
+				Answers: []standup.Answer{
+					standup.Answer{Text: "Reviews"},
+					standup.Answer{Text: "Docs"},
+				},
+			},
+		},
+		Setting: setting.Setting{TargetChannelID: "C1"},
+	}
+}
+
+func buttonPayload(actionID string) (slackapi.InteractionCallback, slackapi.Action) {
+	action := slackapi.Action{
+		ActionID:        actionID,
+		Value:           summary.ActionValue("U1", "2019-01-02", 0),
+		ActionTimestamp: "1.5",
+	}
+	payload := slackapi.InteractionCallback{
+		Type:    "block_actions",
+		Team:    slackapi.Team{ID: "T1"},
+		User:    slackapi.UserRef{ID: "U1"},
+		Channel: slackapi.Channel{ID: "D1"},
+		Actions: []slackapi.Action{action},
+	}
+
+	return payload, action
+}
+
+func TestSameAsYesterday(t *testing.T) {
+	mocked := newMockedDynamo()
+	db := dynamo.NewFromIface(mocked)
+	cl := &slackapi.Fake{}
+
+	payload, action := buttonPayload(questions.SameAsYesterdayActionID)
+	if err := sameAsYesterday(context.Background(), db, cl, cl, payload, action); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if mocked.Saved == nil || len(mocked.Saved.Answers) != 1 {
+		t.Fatalf("Want an answer to be saved, got %+v", mocked.Saved)
+	}
+	want := standup.Answer{Text: "Reviews", Raw: "Reviews", PostedAt: "1.5"}
+	if got := mocked.Saved.Answers[0]; got.Text != want.Text || got.Raw != want.Raw || got.PostedAt != want.PostedAt {
+		t.Fatalf("Want %+v, got %+v", want, got)
+	}
+
+	if len(cl.Updated) != 1 || cl.Updated[0].ChannelID != "D1" || cl.Updated[0].Timestamp != "1.0" {
+		t.Fatalf("Want the buttons to be taken off the question, got %+v", cl.Updated)
+	}
+}
+
+func TestSameAsYesterdayOfOtherMember(t *testing.T) {
+	mocked := newMockedDynamo()
+	db := dynamo.NewFromIface(mocked)
+	cl := &slackapi.Fake{}
+
+	payload, action := buttonPayload(questions.SameAsYesterdayActionID)
+	payload.User.ID = "U2"
+	if err := sameAsYesterday(context.Background(), db, cl, cl, payload, action); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if mocked.Saved != nil || len(cl.Updated) != 0 {
+		t.Fatalf("Want nothing answered, got %+v", mocked.Saved)
+	}
+}
+
+func TestSameAsPlanned(t *testing.T) {
+	mocked := newMockedDynamo()
+	mocked.Setting.Questions = []string{"What did you do yesterday?", "What will you do today?"}
+	mocked.Setting.PlanQuestion = 2
+	mocked.Setting.RecapQuestion = 1
+	db := dynamo.NewFromIface(mocked)
+	cl := &slackapi.Fake{}
+
+	payload, action := buttonPayload(questions.SameAsPlannedActionID)
+	if err := sameAsPlanned(context.Background(), db, cl, cl, payload, action); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if mocked.Saved == nil || len(mocked.Saved.Answers) != 1 {
+		t.Fatalf("Want an answer to be saved, got %+v", mocked.Saved)
+	}
+	if got := mocked.Saved.Answers[0]; got.Text != "Docs" || got.Raw != "Docs" || got.PostedAt != "1.5" {
+		t.Fatalf("Want the plan of yesterday, got %+v", got)
+	}
+
+	if len(cl.Updated) != 1 || !strings.Contains(string(cl.Updated[0].Message.Blocks), "Same as planned") {
+		t.Fatalf("Want the buttons to be taken off the question, got %+v", cl.Updated)
+	}
+}
+
+func TestSameAsPlannedWithoutCarryOver(t *testing.T) {
+	mocked := newMockedDynamo()
+	db := dynamo.NewFromIface(mocked)
+	cl := &slackapi.Fake{}
+
+	payload, action := buttonPayload(questions.SameAsPlannedActionID)
+	if err := sameAsPlanned(context.Background(), db, cl, cl, payload, action); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if mocked.Saved != nil || len(cl.Updated) != 0 {
+		t.Fatalf("Want nothing answered, got %+v", mocked.Saved)
+	}
+}
@@ -0,0 +1,237 @@
+package interactive
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/job"
+	"github.com/tsub/serverless-daily-standup-bot/internal/schedule"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/summary"
+	"github.com/tsub/serverless-daily-standup-bot/internal/util"
+)
+
+// JobType is the job saving a submitted setting dialog.
+const JobType = "interactive.setting"
+
+type dialogError struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+func init() {
+	job.Register(JobType, func(ctx context.Context, payload json.RawMessage) error {
+		var callback slackapi.InteractionCallback
+		if err := json.Unmarshal(payload, &callback); err != nil {
+			return err
+		}
+
+		return initialSettings(ctx, callback)
+	})
+}
+
+// Accept validates an interaction payload posted as a form and enqueues the
+// work, returning the status code and JSON body of the response.
+func Accept(ctx context.Context, q job.Queue, body []byte) (int, string, error) {
+	query, err := url.ParseQuery(string(body))
+	if err != nil {
+		return 400, "", nil
+	}
+
+	var payload slackapi.InteractionCallback
+	if err := json.Unmarshal([]byte(query.Get("payload")), &payload); err != nil {
+		return 400, "", err
+	}
+
+	// for debug
+	log.Printf("payload: %v", payload)
+
+	if payload.Type == "block_actions" {
+		return acceptActions(ctx, q, payload)
+	}
+
+	switch payload.CallbackID {
+	case "setting":
+		if errs := validateSetting(payload.Submission); len(errs) > 0 {
+			b, err := json.Marshal(map[string][]dialogError{"errors": errs})
+			if err != nil {
+				return 500, "", err
+			}
+
+			return 200, string(b), nil
+		}
+
+		j, err := job.New(JobType, payload)
+		if err != nil {
+			return 500, "", err
+		}
+
+		if err := q.Enqueue(ctx, j); err != nil {
+			return 500, "", err
+		}
+
+		return 200, "", nil
+	default:
+		return 200, "", nil
+	}
+}
+
+// validateSetting checks a setting dialog so that mistakes are shown in
+// the dialog instead of failing later in the worker.
+func validateSetting(submission map[string]string) []dialogError {
+	var errs []dialogError
+
+	if submission["members_source"] != "channel" && strings.TrimSpace(submission["user_ids"]) == "" {
+		errs = append(errs, dialogError{Name: "user_ids", Error: "Please type at least one member"})
+	}
+	if strings.TrimSpace(submission["questions"]) == "" {
+		errs = append(errs, dialogError{Name: "questions", Error: "Please write at least one question"})
+	}
+	if strings.TrimSpace(submission["target_channel_id"]) == "" {
+		errs = append(errs, dialogError{Name: "target_channel_id", Error: "Please choose a channel"})
+	}
+
+	if _, err := setting.ParseDestinations(submission["destinations"]); err != nil {
+		errs = append(errs, dialogError{Name: "destinations", Error: err.Error()})
+	}
+
+	questions := len(strings.Split(submission["questions"], "\n"))
+	if _, _, err := setting.ParseBlockerQuestion(submission["blockers"], questions); err != nil {
+		errs = append(errs, dialogError{Name: "blockers", Error: err.Error()})
+	}
+
+	if _, _, err := setting.ParseCarryOver(submission["carry_over"], questions); err != nil {
+		errs = append(errs, dialogError{Name: "carry_over", Error: err.Error()})
+	}
+
+	if err := summary.Validate(submission["summary_template"], questions); err != nil {
+		errs = append(errs, dialogError{Name: "summary_template", Error: err.Error()})
+	}
+
+	if _, err := schedule.Parse(submission["schedule_expression"]); err != nil {
+		errs = append(errs, dialogError{Name: "schedule_expression", Error: err.Error()})
+	}
+
+	return errs
+}
+
+func initialSettings(ctx context.Context, payload slackapi.InteractionCallback) error {
+	db := dynamo.New(session.New())
+
+	targetChannelID := payload.Submission["target_channel_id"]
+	questions := util.Map(strings.Split(payload.Submission["questions"], "\n"), strings.TrimSpace)
+	userIDs := util.Map(strings.Split(payload.Submission["user_ids"], "\n"), strings.TrimSpace)
+	scheduleExpression := strings.TrimSpace(payload.Submission["schedule_expression"])
+	membersFromChannel := payload.Submission["members_source"] == "channel"
+	teamID := payload.Team.ID
+	replyChannelID := payload.Channel.ID
+
+	cl, _, err := installation.Clients(db, teamID)
+	if err != nil {
+		return err
+	}
+
+	destinations, err := setting.ParseDestinations(payload.Submission["destinations"])
+	if err != nil {
+		return err
+	}
+
+	blockerQuestion, escalationChannelID, err := setting.ParseBlockerQuestion(payload.Submission["blockers"], len(questions))
+	if err != nil {
+		return err
+	}
+
+	planQuestion, recapQuestion, err := setting.ParseCarryOver(payload.Submission["carry_over"], len(questions))
+	if err != nil {
+		return err
+	}
+
+	if membersFromChannel {
+		userIDs, err = channelMembers(ctx, cl, targetChannelID)
+		if err != nil {
+			return err
+		}
+	}
+
+	s := setting.Setting{
+		TargetChannelID:     targetChannelID,
+		Questions:           questions,
+		UserIDs:             userIDs,
+		TeamID:              teamID,
+		MembersFromChannel:  membersFromChannel,
+		Threaded:            payload.Submission["summary_style"] == "thread",
+		SummaryTemplate:     strings.TrimSpace(payload.Submission["summary_template"]),
+		Destinations:        destinations,
+		BlockerQuestion:     blockerQuestion,
+		EscalationChannelID: escalationChannelID,
+		PlanQuestion:        planQuestion,
+		RecapQuestion:       recapQuestion,
+	}
+	// Not part of the dialog
+	if prev, err := setting.Get(db, targetChannelID); err == nil {
+		s.NotifyMentions = prev.NotifyMentions
+	} else if err != dynamo.ErrNotFound {
+		return err
+	}
+
+	if err := s.Save(db); err != nil {
+		return err
+	}
+
+	err = schedule.Current().Put(ctx, teamID, targetChannelID, scheduleExpression)
+	if err != nil {
+		return err
+	}
+
+	_, err = cl.PostMessage(ctx, replyChannelID, slackapi.Message{Text: "Setting finished"})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// channelMembers returns the human members of a channel. Later joins and
+// leaves are applied from the member_joined_channel and member_left_channel
+// events.
+func channelMembers(ctx context.Context, cl slackapi.Client, channelID string) ([]string, error) {
+	members, err := cl.GetConversationMembers(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var userIDs []string
+
+	err = util.Each(10, members, func(userID string) error {
+		u, err := cl.GetUserInfo(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if u.IsBot || u.Deleted {
+			return nil
+		}
+
+		mu.Lock()
+		userIDs = append(userIDs, userID)
+		mu.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(userIDs)
+
+	return userIDs, nil
+}
@@ -0,0 +1,147 @@
+package interactive
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tsub/serverless-daily-standup-bot/internal/job"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+)
+
+type recordingQueue struct {
+	Jobs []job.Job
+}
+
+func (q *recordingQueue) Enqueue(ctx context.Context, j job.Job) error {
+	q.Jobs = append(q.Jobs, j)
+	return nil
+}
+
+func encode(t *testing.T, payload slackapi.InteractionCallback) []byte {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	return []byte(url.Values{"payload": {string(b)}}.Encode())
+}
+
+func TestAcceptEnqueuesSetting(t *testing.T) {
+	q := &recordingQueue{}
+
+	body := encode(t, slackapi.InteractionCallback{
+		CallbackID: "setting",
+		Submission: map[string]string{
+			"user_ids":            "U1\nU2",
+			"questions":           "q1",
+			"target_channel_id":   "C1",
+			"schedule_expression": "cron(0 1 ? * MON-FRI *)",
+		},
+	})
+
+	status, respBody, err := Accept(context.Background(), q, body)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if status != 200 || respBody != "" || len(q.Jobs) != 1 {
+		t.Fatalf("Unexpected status %d, body %q, jobs %+v", status, respBody, q.Jobs)
+	}
+}
+
+func TestAcceptRejectsInvalidSetting(t *testing.T) {
+	q := &recordingQueue{}
+
+	body := encode(t, slackapi.InteractionCallback{
+		CallbackID: "setting",
+		Submission: map[string]string{
+			"user_ids":            "U1",
+			"questions":           " ",
+			"target_channel_id":   "C1",
+			"summary_template":    "{{.User.Name",
+			"schedule_expression": "every day",
+		},
+	})
+
+	status, respBody, err := Accept(context.Background(), q, body)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if status != 200 || len(q.Jobs) != 0 {
+		t.Fatalf("Unexpected status %d, jobs %+v", status, q.Jobs)
+	}
+
+	if !strings.Contains(respBody, `"questions"`) || !strings.Contains(respBody, `"summary_template"`) || !strings.Contains(respBody, `"schedule_expression"`) {
+		t.Fatalf("Want dialog errors, got %q", respBody)
+	}
+}
+
+func TestAcceptChannelMembersNeedNoList(t *testing.T) {
+	q := &recordingQueue{}
+
+	body := encode(t, slackapi.InteractionCallback{
+		CallbackID: "setting",
+		Submission: map[string]string{
+			"members_source":      "channel",
+			"questions":           "q1",
+			"target_channel_id":   "C1",
+			"schedule_expression": "cron(0 1 ? * MON-FRI *)",
+		},
+	})
+
+	status, respBody, err := Accept(context.Background(), q, body)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if status != 200 || respBody != "" || len(q.Jobs) != 1 {
+		t.Fatalf("Unexpected status %d, body %q, jobs %+v", status, respBody, q.Jobs)
+	}
+}
+
+func TestChannelMembersSkipsBots(t *testing.T) {
+	cl := &slackapi.Fake{
+		Members: map[string][]string{"C1": {"U2", "B1", "U1"}},
+		Users: map[string]slackapi.User{
+			"U1": {ID: "U1"},
+			"U2": {ID: "U2"},
+			"B1": {ID: "B1", IsBot: true},
+		},
+	}
+
+	userIDs, err := channelMembers(context.Background(), cl, "C1")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if strings.Join(userIDs, ",") != "U1,U2" {
+		t.Fatalf("Want U1,U2, got %v", userIDs)
+	}
+}
+
+func TestAcceptEnqueuesShowMore(t *testing.T) {
+	q := &recordingQueue{}
+
+	body := encode(t, slackapi.InteractionCallback{
+		Type: "block_actions",
+		Team: slackapi.Team{ID: "T1"},
+		User: slackapi.UserRef{ID: "U2"},
+		Actions: []slackapi.Action{
+			slackapi.Action{ActionID: "summary_show_more", Value: "U1/2018-09-03/0"},
+		},
+	})
+
+	status, _, err := Accept(context.Background(), q, body)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	// Keyed by the member of the standup rather than whoever clicked
+	if status != 200 || len(q.Jobs) != 1 || q.Jobs[0].Type != ActionJobType || q.Jobs[0].Key != "T1/U1" {
+		t.Fatalf("Unexpected status %d, jobs %+v", status, q.Jobs)
+	}
+}
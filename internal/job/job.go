@@ -0,0 +1,92 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+var queueURL = os.Getenv("JOB_QUEUE_URL")
+
+// Job is a unit of work deferred by a handler which has to answer Slack
+// within 3 seconds.
+type Job struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	// Key makes a queue run the jobs sharing it one at a time, when it can.
+	Key string `json:"key,omitempty"`
+}
+
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+type Queue interface {
+	Enqueue(ctx context.Context, j Job) error
+}
+
+var (
+	mu       sync.RWMutex
+	handlers = map[string]Handler{}
+)
+
+// Register makes jobs of the type runnable by a worker. Packages register
+// their handlers in init, so a worker only has to import them.
+func Register(jobType string, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	handlers[jobType] = h
+}
+
+// UserKey is the key of the jobs changing the standups of a member, so that
+// they don't overwrite each other.
+func UserKey(teamID string, userID string) string {
+	return teamID + "/" + userID
+}
+
+func New(jobType string, payload interface{}) (Job, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, err
+	}
+
+	return Job{Type: jobType, Payload: b}, nil
+}
+
+// Run executes a job with its registered handler.
+func Run(ctx context.Context, j Job) error {
+	mu.RLock()
+	h, ok := handlers[j.Type]
+	mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown job type: %s", j.Type)
+	}
+
+	return h(ctx, j.Payload)
+}
+
+// FromEnv returns the SQS queue at JOB_QUEUE_URL, or an in-memory queue
+// for local runs when it isn't set.
+func FromEnv() Queue {
+	if queueURL == "" {
+		return defaultMemory()
+	}
+
+	return NewSQS(session.New(), queueURL)
+}
+
+var (
+	memoryOnce sync.Once
+	memory     *Memory
+)
+
+func defaultMemory() *Memory {
+	memoryOnce.Do(func() {
+		memory = NewMemory(context.Background(), 4)
+	})
+
+	return memory
+}
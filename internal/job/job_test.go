@@ -0,0 +1,146 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+type mockedSQS struct {
+	sqsiface.SQSAPI
+	Bodies []string
+	Groups []string
+}
+
+func (m *mockedSQS) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, options ...request.Option) (*sqs.SendMessageOutput, error) {
+	m.Bodies = append(m.Bodies, *input.MessageBody)
+	m.Groups = append(m.Groups, aws.StringValue(input.MessageGroupId))
+	return &sqs.SendMessageOutput{}, nil
+}
+
+type testPayload struct {
+	Value string `json:"value"`
+}
+
+func TestMemorySuccess(t *testing.T) {
+	got := make(chan string, 1)
+	Register("test.memory", func(ctx context.Context, payload json.RawMessage) error {
+		var p testPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		got <- p.Value
+		return nil
+	})
+
+	j, err := New("test.memory", testPayload{Value: "hello"})
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	q := NewMemory(context.Background(), 1)
+	if err := q.Enqueue(context.Background(), j); err != nil {
+		t.Fatalf("%q", err)
+	}
+	q.Close()
+
+	if v := <-got; v != "hello" {
+		t.Fatalf("Want %q, got %q", "hello", v)
+	}
+}
+
+func TestMemoryRunsKeyOneAtATime(t *testing.T) {
+	var running, overlaps int32
+	Register("test.keyed", func(ctx context.Context, payload json.RawMessage) error {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+
+	q := NewMemory(context.Background(), 4)
+	for i := 0; i < 8; i++ {
+		if err := q.Enqueue(context.Background(), Job{Type: "test.keyed", Key: "user/2019-01-01"}); err != nil {
+			t.Fatalf("%q", err)
+		}
+	}
+	q.Close()
+
+	if overlaps != 0 {
+		t.Fatalf("Want jobs with the same key to run one at a time, got %d overlaps", overlaps)
+	}
+}
+
+func TestMemoryRetriesFailedJob(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = time.Millisecond
+
+	var attempts int32
+	Register("test.retry", func(ctx context.Context, payload json.RawMessage) error {
+		if atomic.AddInt32(&attempts, 1) < maxAttempts {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	q := NewMemory(context.Background(), 1)
+	if err := q.Enqueue(context.Background(), Job{Type: "test.retry"}); err != nil {
+		t.Fatalf("%q", err)
+	}
+	q.Close()
+
+	if attempts != maxAttempts {
+		t.Fatalf("Want %d attempts, got %d", maxAttempts, attempts)
+	}
+}
+
+func TestSQSSuccess(t *testing.T) {
+	mocked := &mockedSQS{}
+	q := &SQS{client: mocked, url: "https://sqs.example.com/queue"}
+
+	j, err := New("test.sqs", testPayload{Value: "hello"})
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if err := q.Enqueue(context.Background(), j); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	j.Key = UserKey("T1", "U1")
+	if err := q.Enqueue(context.Background(), j); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(mocked.Bodies) != 2 {
+		t.Fatalf("Want 2 messages, got %d", len(mocked.Bodies))
+	}
+	if mocked.Groups[0] != "test.sqs" || mocked.Groups[1] != "T1/U1" {
+		t.Fatalf("Unexpected message groups: %v", mocked.Groups)
+	}
+
+	decoded, err := Decode(mocked.Bodies[0])
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if decoded.Type != "test.sqs" || string(decoded.Payload) != `{"value":"hello"}` {
+		t.Fatalf("Unexpected job: %+v", decoded)
+	}
+}
+
+func TestRunUnknownType(t *testing.T) {
+	if err := Run(context.Background(), Job{Type: "test.unknown"}); err == nil {
+		t.Fatal("Want an error for an unknown job type")
+	}
+}
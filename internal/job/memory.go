@@ -0,0 +1,118 @@
+package job
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const maxAttempts = 3
+
+// retryDelay is the wait before the second attempt of a failed job, which
+// doubles on each later one.
+var retryDelay = time.Second
+
+// Memory runs jobs on goroutines of the current process. It only fits a
+// long-running process, since a frozen Lambda container would stall it.
+//
+// Jobs with the same key run one at a time, and a failed job is retried
+// with a backoff before it's dropped.
+type Memory struct {
+	jobs chan Job
+	wg   sync.WaitGroup
+
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	waiting int
+}
+
+func NewMemory(ctx context.Context, workers int) *Memory {
+	m := &Memory{jobs: make(chan Job, 100), locks: map[string]*keyLock{}}
+
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+
+			for j := range m.jobs {
+				m.run(ctx, j)
+			}
+		}()
+	}
+
+	return m
+}
+
+func (m *Memory) run(ctx context.Context, j Job) {
+	if j.Key != "" {
+		unlock := m.lock(j.Key)
+		defer unlock()
+	}
+
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := Run(ctx, j)
+		if err == nil {
+			return
+		}
+
+		if attempt == maxAttempts {
+			log.Printf("job %s failed after %d attempts: %s", j.Type, attempt, err)
+			return
+		}
+		log.Printf("job %s failed, retrying in %s: %s", j.Type, delay, err)
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// lock waits until no other job with the key is running, and returns the
+// function letting the next one run.
+func (m *Memory) lock(key string) func() {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyLock{}
+		m.locks[key] = l
+	}
+	l.waiting++
+	m.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		m.mu.Lock()
+		l.waiting--
+		if l.waiting == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
+
+func (m *Memory) Enqueue(ctx context.Context, j Job) error {
+	select {
+	case m.jobs <- j:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting jobs and waits for the queued ones to finish.
+func (m *Memory) Close() {
+	close(m.jobs)
+	m.wg.Wait()
+}
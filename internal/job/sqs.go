@@ -0,0 +1,51 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// SQS hands jobs over to the worker function through a FIFO SQS queue. Jobs
+// with the same key share a message group, which the worker receives in
+// order and one at a time.
+type SQS struct {
+	client sqsiface.SQSAPI
+	url    string
+}
+
+func NewSQS(p client.ConfigProvider, url string) *SQS {
+	return &SQS{client: sqs.New(p), url: url}
+}
+
+func (q *SQS) Enqueue(ctx context.Context, j Job) error {
+	body, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	group := j.Key
+	if group == "" {
+		group = j.Type
+	}
+
+	_, err = q.client.SendMessageWithContext(ctx, &sqs.SendMessageInput{
+		QueueUrl:       aws.String(q.url),
+		MessageBody:    aws.String(string(body)),
+		MessageGroupId: aws.String(group),
+	})
+
+	return err
+}
+
+// Decode reads a job back from the body of an SQS message.
+func Decode(body string) (Job, error) {
+	var j Job
+	err := json.Unmarshal([]byte(body), &j)
+
+	return j, err
+}
@@ -0,0 +1,119 @@
+// Package mrkdwn reads the markup of Slack messages, e.g. "<@U0123ABCD>"
+// for a mention or "<https://example.com|a link>" for a link.
+// see https://api.slack.com/reference/surfaces/formatting
+package mrkdwn
+
+import (
+	"regexp"
+	"strings"
+)
+
+var tag = regexp.MustCompile(`<([^<>]*)>`)
+
+var (
+	escape   = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	unescape = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+)
+
+// Normalize returns the text of a message in the form answers are stored
+// in: without surrounding spaces, Windows line breaks or the names of legacy
+// user mentions such as "<@U0123ABCD|jane>".
+func Normalize(text string) string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+
+	text = tag.ReplaceAllStringFunc(text, func(t string) string {
+		inner := t[1 : len(t)-1]
+		if isUser(inner) {
+			return "<" + strings.SplitN(inner, "|", 2)[0] + ">"
+		}
+		return t
+	})
+
+	return strings.TrimSpace(text)
+}
+
+// Mentions returns the users mentioned in a text, once each.
+func Mentions(text string) []string {
+	var userIDs []string
+	seen := map[string]bool{}
+
+	for _, m := range tag.FindAllStringSubmatch(text, -1) {
+		if !isUser(m[1]) {
+			continue
+		}
+
+		userID := strings.SplitN(m[1][1:], "|", 2)[0]
+		if !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	return userIDs
+}
+
+// ResolveMentions replaces the user mentions of a text with the names of
+// the users, which shows them without notifying anyone. Links and the rest
+// of the markup are kept.
+func ResolveMentions(text string, names map[string]string) string {
+	return tag.ReplaceAllStringFunc(text, func(t string) string {
+		inner := t[1 : len(t)-1]
+		if !isUser(inner) {
+			return t
+		}
+
+		return escape.Replace("@" + name(inner, names))
+	})
+}
+
+// Plain returns a text without markup, e.g. for exports: mentions become
+// names and links show their URL.
+func Plain(text string, names map[string]string) string {
+	text = tag.ReplaceAllStringFunc(text, func(t string) string {
+		inner := t[1 : len(t)-1]
+		target, label := inner, ""
+		if i := strings.Index(inner, "|"); i >= 0 {
+			target, label = inner[:i], inner[i+1:]
+		}
+
+		switch {
+		case isUser(inner):
+			return "@" + name(inner, names)
+		case strings.HasPrefix(target, "#"):
+			if label != "" {
+				return "#" + label
+			}
+			return target
+		case strings.HasPrefix(target, "!"):
+			// Special mentions, user groups and dates
+			if label != "" {
+				return label
+			}
+			return "@" + strings.TrimPrefix(target, "!")
+		case strings.HasPrefix(target, "mailto:"):
+			return strings.TrimPrefix(target, "mailto:")
+		case label != "" && label != target:
+			return label + " (" + target + ")"
+		default:
+			return target
+		}
+	})
+
+	return unescape.Replace(text)
+}
+
+func isUser(inner string) bool {
+	return strings.HasPrefix(inner, "@U") || strings.HasPrefix(inner, "@W")
+}
+
+func name(inner string, names map[string]string) string {
+	parts := strings.SplitN(inner[1:], "|", 2)
+	if n := names[parts[0]]; n != "" {
+		return n
+	}
+	if len(parts) == 2 && parts[1] != "" {
+		return parts[1]
+	}
+
+	return parts[0]
+}
@@ -0,0 +1,37 @@
+package mrkdwn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	got := Normalize("  Paired with <@U1|jane>\r\nsee <https://example.com|the PR>  ")
+	want := "Paired with <@U1>\nsee <https://example.com|the PR>"
+	if got != want {
+		t.Fatalf("Want %q, got %q", want, got)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	got := Mentions("<@U1> and <@W2|bob>, again <@U1> in <#C1|general> <!here>")
+	if want := []string{"U1", "W2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Want %v, got %v", want, got)
+	}
+}
+
+func TestResolveMentions(t *testing.T) {
+	got := ResolveMentions("Ask <@U1> about <https://example.com|the PR>, or <@U2>", map[string]string{"U1": "Jane <Doe>"})
+	want := "Ask @Jane &lt;Doe&gt; about <https://example.com|the PR>, or @U2"
+	if got != want {
+		t.Fatalf("Want %q, got %q", want, got)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	got := Plain("<@U1> in <#C1|general>: <https://example.com|the PR>, <https://example.com/a>, <mailto:a@example.com|a@example.com>, <!here> &lt;3 &amp; more", map[string]string{"U1": "Jane"})
+	want := "@Jane in #general: the PR (https://example.com), https://example.com/a, a@example.com, @here <3 & more"
+	if got != want {
+		t.Fatalf("Want %q, got %q", want, got)
+	}
+}
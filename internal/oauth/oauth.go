@@ -0,0 +1,177 @@
+package oauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/schedule"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/util"
+)
+
+var clientID = os.Getenv("SLACK_CLIENT_ID")
+var clientSecret = os.Getenv("SLACK_CLIENT_SECRET")
+var redirectURI = os.Getenv("SLACK_REDIRECT_URI")
+
+const authorizeURL = "https://slack.com/oauth/v2/authorize"
+
+// Scopes used by the bot: DMs for questions, channels for summaries, user
+// info for time zones and the slash command.
+var botScopes = []string{
+	"channels:read",
+	"chat:write",
+	"commands",
+	"groups:read",
+	"im:history",
+	"im:read",
+	"im:write",
+	"users.profile:read",
+	"users:read",
+}
+
+// Scopes of the installing user, whose token reads users and profiles like
+// SLACK_TOKEN does for a single workspace.
+var userScopes = []string{
+	"users.profile:read",
+	"users:read",
+}
+
+// A state is only accepted for a while after the install page was opened.
+const stateTTL = 10 * time.Minute
+
+// InstallURL returns the Slack page asking a user to install the app.
+func InstallURL(now time.Time) string {
+	query := url.Values{
+		"client_id":    {clientID},
+		"scope":        {strings.Join(botScopes, ",")},
+		"user_scope":   {strings.Join(userScopes, ",")},
+		"redirect_uri": {redirectURI},
+		"state":        {newState(now)},
+	}
+
+	return authorizeURL + "?" + query.Encode()
+}
+
+// Redirect completes an installation from the query Slack redirects the
+// user with, and returns the status code and HTML body to show them.
+func Redirect(ctx context.Context, query url.Values) (int, string, error) {
+	if e := query.Get("error"); e != "" {
+		return 200, page("Installation canceled."), nil
+	}
+
+	if !validState(query.Get("state"), time.Now()) {
+		return 400, page("This installation link has expired, please try again."), nil
+	}
+
+	resp, err := slackapi.ExchangeCode(ctx, slackapi.New(""), clientID, clientSecret, query.Get("code"), redirectURI)
+	if err != nil {
+		return 500, page("Installation failed."), err
+	}
+
+	db := dynamo.New(session.New())
+
+	i := installation.Installation{
+		TeamID:      resp.Team.ID,
+		TeamName:    resp.Team.Name,
+		AppID:       resp.AppID,
+		BotUserID:   resp.BotUserID,
+		BotToken:    resp.AccessToken,
+		Scope:       resp.Scope,
+		UserID:      resp.AuthedUser.ID,
+		UserToken:   resp.AuthedUser.AccessToken,
+		UserScope:   resp.AuthedUser.Scope,
+		InstalledAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := installation.Save(db, i); err != nil {
+		return 500, page("Installation failed."), err
+	}
+
+	if err := enableTeam(ctx, db, i.TeamID); err != nil {
+		return 500, page("Installation failed."), err
+	}
+
+	log.Printf("installed to team: %s", i.TeamID)
+
+	return 200, page(fmt.Sprintf("Installed to %s. Run /standup setting in a channel to get started.", html.EscapeString(i.TeamName))), nil
+}
+
+// enableTeam resumes the settings of a team disabled when the app was
+// uninstalled, so that a reinstall picks its stand-ups up again.
+func enableTeam(ctx context.Context, db *dynamo.DB, teamID string) error {
+	settings, err := setting.ByTeam(db, teamID)
+	if err != nil {
+		return err
+	}
+
+	var errs util.Errors
+	enabled := 0
+	for _, s := range settings {
+		if !s.Disabled {
+			continue
+		}
+
+		if err := setting.SetDisabled(db, s.TargetChannelID, false); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := schedule.Current().Put(ctx, teamID, s.TargetChannelID, s.ScheduleExpression); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		enabled++
+	}
+
+	if enabled > 0 {
+		log.Printf("enabled %d settings of team %s", enabled, teamID)
+	}
+
+	return errs.Err()
+}
+
+// newState signs the current time so that the redirect can be checked
+// without storing anything.
+func newState(now time.Time) string {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	return ts + "." + sign(ts)
+}
+
+func validState(state string, now time.Time) bool {
+	parts := strings.SplitN(state, ".", 2)
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(sign(parts[0]))) {
+		return false
+	}
+
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return now.Sub(time.Unix(ts, 0)) <= stateTTL
+}
+
+func sign(s string) string {
+	mac := hmac.New(sha256.New, []byte(clientSecret))
+	mac.Write([]byte(s))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// page wraps a message, with any text from Slack escaped already.
+func page(message string) string {
+	return "<!DOCTYPE html><html><body><p>" + message + "</p></body></html>"
+}
@@ -0,0 +1,121 @@
+package oauth
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/schedule"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+)
+
+// mockedDynamo serves the settings of a team and records the ones updated.
+type mockedDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	Settings []setting.Setting
+	Updated  []string
+}
+
+func (m *mockedDynamo) ScanWithContext(context aws.Context, input *dynamodb.ScanInput, options ...request.Option) (*dynamodb.ScanOutput, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, s := range m.Settings {
+		item, err := dynamo.MarshalItem(s)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return &dynamodb.ScanOutput{Items: items, Count: aws.Int64(int64(len(items)))}, nil
+}
+
+func (m *mockedDynamo) UpdateItemWithContext(context aws.Context, input *dynamodb.UpdateItemInput, options ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	m.Updated = append(m.Updated, aws.StringValue(input.Key["target_channel_id"].S))
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+// recordingScheduler records the schedules put.
+type recordingScheduler struct {
+	Schedules map[string]string
+}
+
+func (s *recordingScheduler) Get(ctx context.Context, teamID string, targetChannelID string) (string, error) {
+	return s.Schedules[targetChannelID], nil
+}
+
+func (s *recordingScheduler) Put(ctx context.Context, teamID string, targetChannelID string, expr string) error {
+	s.Schedules[targetChannelID] = expr
+	return nil
+}
+
+func (s *recordingScheduler) Disable(ctx context.Context, teamID string, targetChannelID string) error {
+	return nil
+}
+
+func TestInstallURLSuccess(t *testing.T) {
+	clientID = "client"
+	clientSecret = "secret"
+	redirectURI = "https://example.com/oauth/redirect"
+
+	u, err := url.Parse(InstallURL(time.Now()))
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	query := u.Query()
+	if query.Get("client_id") != "client" || query.Get("redirect_uri") != redirectURI || query.Get("scope") == "" || query.Get("user_scope") == "" {
+		t.Fatalf("Unexpected query: %v", query)
+	}
+
+	if !validState(query.Get("state"), time.Now()) {
+		t.Fatalf("Want state %q to be valid", query.Get("state"))
+	}
+}
+
+func TestValidStateFailure(t *testing.T) {
+	clientSecret = "secret"
+	now := time.Now()
+
+	cases := []string{
+		"",
+		"123",
+		newState(now)[:12] + "0",
+		newState(now.Add(-stateTTL - time.Minute)),
+	}
+
+	for _, state := range cases {
+		if validState(state, now) {
+			t.Errorf("Want state %q to be invalid", state)
+		}
+	}
+}
+
+func TestEnableTeamAfterReinstall(t *testing.T) {
+	sched := &recordingScheduler{Schedules: map[string]string{}}
+	schedule.Use(sched)
+	defer schedule.Use(nil)
+
+	mocked := &mockedDynamo{Settings: []setting.Setting{
+		setting.Setting{TeamID: "T1", TargetChannelID: "C1", ScheduleExpression: "cron(0 1 ? * MON-FRI *)", Disabled: true},
+		setting.Setting{TeamID: "T1", TargetChannelID: "C2", ScheduleExpression: "cron(0 2 ? * MON-FRI *)"},
+	}}
+	db := dynamo.NewFromIface(mocked)
+
+	if err := enableTeam(context.Background(), db, "T1"); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if !reflect.DeepEqual(mocked.Updated, []string{"C1"}) {
+		t.Fatalf("Want C1 to be enabled, got %v", mocked.Updated)
+	}
+	if !reflect.DeepEqual(sched.Schedules, map[string]string{"C1": "cron(0 1 ? * MON-FRI *)"}) {
+		t.Fatalf("Want the schedule of C1 to be put, got %v", sched.Schedules)
+	}
+}
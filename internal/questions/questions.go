@@ -0,0 +1,582 @@
+package questions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/blocker"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/job"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/summary"
+	"github.com/tsub/serverless-daily-standup-bot/internal/thread"
+	"github.com/tsub/serverless-daily-standup-bot/internal/usercache"
+	"github.com/tsub/serverless-daily-standup-bot/internal/util"
+)
+
+// JobType is the job reacting to a changed standup, for when there are no
+// DynamoDB Streams to do it.
+const JobType = "questions.process"
+
+// Key identifies the standup which has changed.
+type Key struct {
+	TeamID string `json:"team_id"`
+	UserID string `json:"user_id"`
+	Date   string `json:"date"`
+}
+
+func init() {
+	job.Register(JobType, func(ctx context.Context, payload json.RawMessage) error {
+		var key Key
+		if err := json.Unmarshal(payload, &key); err != nil {
+			return err
+		}
+
+		return Process(ctx, key)
+	})
+}
+
+// Process sends the next question of a standup, or posts its summary to
+// the target channel once every question has been answered.
+func Process(ctx context.Context, key Key) error {
+	userID := key.UserID
+
+	db := dynamo.New(session.New())
+
+	botcl, cl, err := installation.Clients(db, key.TeamID)
+	if err != nil {
+		return err
+	}
+
+	userInfoResp, err := usercache.Get(ctx, db, cl, userID)
+	if err != nil {
+		return err
+	}
+
+	s, err := standup.Get(db, userID, key.Date, true)
+	if err != nil {
+		return err
+	}
+
+	targetChannelID := s.TargetChannelID
+
+	switch s.Status {
+	case standup.StatusPending, standup.StatusAsking:
+		if err := sendNextQuestion(ctx, db, botcl, s); err != nil {
+			return err
+		}
+
+		if len(s.Summaries) == 0 && s.FinishedAt == "" {
+			return nil
+		}
+		// An answer was deleted after the summary was posted, update it
+		// until the question is answered again
+	case standup.StatusCompleted:
+		// Send message summary if finished
+		log.Printf("finished user: %s", userID)
+	default:
+		// Canceled, skipped or expired, nothing to post
+		return nil
+	}
+	finished := s.Status == standup.StatusCompleted
+
+	st, err := setting.Get(db, targetChannelID)
+	if err == dynamo.ErrNotFound {
+		st = &setting.Setting{TargetChannelID: targetChannelID}
+	} else if err != nil {
+		return err
+	}
+
+	data := summary.NewData(s, userInfoResp)
+	data.MarkBlocker(st.BlockerIndex())
+
+	if finished {
+		if err := trackBlocker(db, st, s, &data); err != nil {
+			return err
+		}
+	}
+
+	mentions := data.Mentions()
+	data.Resolve(mentionNames(ctx, db, cl, mentions))
+
+	if len(data.Answered()) == 0 {
+		if !finished {
+			// Every answer left was deleted
+			return retract(ctx, db, botcl, s)
+		}
+
+		// Skip if every answer is "none"
+		return nil
+	}
+
+	var errs util.Errors
+
+	postedTarget, err := publish(ctx, db, botcl, st, s, data)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if postedTarget {
+		if err := confirm(ctx, botcl, s); err != nil {
+			errs = append(errs, err)
+		}
+
+		if st.NotifyMentions {
+			if err := notifyMentions(ctx, botcl, s, mentions); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if finished {
+		if err := escalate(ctx, db, botcl, st, s, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.Err()
+}
+
+// publish posts the summary to the destinations which don't have it yet and
+// updates the others, e.g. after an answer was edited. It reports whether
+// the summary was posted to the target channel.
+func publish(ctx context.Context, db *dynamo.DB, cl slackapi.Client, st *setting.Setting, s *standup.Standup, data summary.Data) (bool, error) {
+	finished := s.Status == standup.StatusCompleted
+
+	var posted []standup.Summary
+	var postedTarget bool
+	var errs util.Errors
+
+	for _, d := range st.AllDestinations() {
+		msg, ok := render(st, d.Format, data)
+
+		channelID := d.ChannelID
+		if isUser(channelID) {
+			var err error
+			channelID, err = cl.OpenDM(ctx, d.ChannelID)
+			if err != nil {
+				errs = appendUnlessUnreachable(errs, d.ChannelID, err)
+				continue
+			}
+		}
+
+		if ts := s.SummaryTimestamp(channelID); ts != "" {
+			if err := cl.UpdateMessage(ctx, channelID, ts, msg); err != nil {
+				errs = appendUnlessUnreachable(errs, channelID, err)
+			}
+			continue
+		}
+
+		if !ok || !finished {
+			continue
+		}
+
+		if channelID == s.TargetChannelID {
+			msg.ThreadTS = s.ThreadTS
+		}
+
+		ts, err := cl.PostMessage(ctx, channelID, msg)
+		if err != nil {
+			errs = appendUnlessUnreachable(errs, channelID, err)
+			continue
+		}
+		posted = append(posted, standup.Summary{ChannelID: channelID, Timestamp: ts})
+		postedTarget = postedTarget || channelID == s.TargetChannelID
+	}
+
+	if len(posted) > 0 {
+		// Record what was posted even if another destination failed, so
+		// that a retry doesn't post it twice
+		if err := s.Finish(db, posted); err != nil {
+			return false, err
+		}
+
+		if s.ThreadTS != "" && postedTarget {
+			if err := updateThread(ctx, db, cl, s); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return postedTarget, errs.Err()
+}
+
+// appendUnlessUnreachable collects the error of a destination, unless the
+// destination can't be posted to until the setting or the channel changes.
+// Retrying would then fail the same way, holding up every standup after it.
+func appendUnlessUnreachable(errs util.Errors, channelID string, err error) util.Errors {
+	if err := skipUnreachable(channelID, err); err != nil {
+		return append(errs, err)
+	}
+
+	return errs
+}
+
+// skipUnreachable logs and drops an error of a channel Slack refuses for
+// good, as retrying would only wedge the job.
+func skipUnreachable(channelID string, err error) error {
+	if slackapi.IsUnreachable(err) {
+		log.Printf("skipped destination %s: %s", channelID, err)
+		return nil
+	}
+
+	return err
+}
+
+// sendNextQuestion sends the question the standup waits an answer for,
+// unless it has already been sent.
+func sendNextQuestion(ctx context.Context, db *dynamo.DB, cl slackapi.Client, s *standup.Standup) error {
+	index := s.NextQuestion()
+	if index >= len(s.Questions) || s.Questions[index].PostedAt != "" {
+		// Skip if already send a next question
+		return nil
+	}
+
+	msg, err := questionMessage(db, s, index)
+	if err != nil {
+		return err
+	}
+
+	if index == 0 && !s.Late && !s.Introduced {
+		if err := introduce(ctx, db, cl, s); err != nil {
+			// The questions are more important than their intro
+			log.Printf("failed to send the intro to %s: %s", s.UserID, err)
+		}
+	}
+
+	ts, err := cl.PostMessage(ctx, s.UserID, msg)
+	if err != nil {
+		return err
+	}
+
+	return s.SentQuestion(db, index, ts)
+}
+
+// introduce sends the intro of the standup to its member, before the first
+// question. It is recorded so that a retry after the question failed to be
+// sent doesn't send it again.
+func introduce(ctx context.Context, db *dynamo.DB, cl slackapi.Client, s *standup.Standup) error {
+	if _, err := cl.PostMessage(ctx, s.UserID, slackapi.Message{Text: intro(s)}); err != nil {
+		return err
+	}
+
+	return s.Introduce(db)
+}
+
+func intro(s *standup.Standup) string {
+	questions := "questions"
+	if len(s.Questions) == 1 {
+		questions = "question"
+	}
+
+	return fmt.Sprintf("Hi! It's time for the stand-up of <#%s>, %d %s today.\n"+
+		"Reply `skip` to skip today, `cancel` to cancel, or `same` to answer a question the same as last time.",
+		s.TargetChannelID, len(s.Questions), questions)
+}
+
+// retract deletes the summaries of a standup left without answers, and
+// counts the member as not done in the thread of the day.
+func retract(ctx context.Context, db *dynamo.DB, cl slackapi.Client, s *standup.Standup) error {
+	summaries := s.Summaries
+	if len(summaries) == 0 && s.FinishedAt != "" {
+		// Standups posted before summaries were tracked per destination
+		summaries = []standup.Summary{standup.Summary{ChannelID: s.TargetChannelID, Timestamp: s.FinishedAt}}
+	}
+
+	for _, summary := range summaries {
+		err := cl.DeleteMessage(ctx, summary.ChannelID, summary.Timestamp)
+		if e, ok := err.(*slackapi.Error); ok && e.Code == "message_not_found" {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.ThreadTS != "" {
+		// Before the summaries are forgotten, which would stop a retry
+		t, err := thread.Unmark(db, s.TargetChannelID, s.Date, s.UserID)
+		if err != nil {
+			return err
+		}
+
+		if err := cl.UpdateMessage(ctx, s.TargetChannelID, t.Timestamp, slackapi.Message{Text: t.Text()}); err != nil {
+			return err
+		}
+	}
+
+	return s.Retract(db)
+}
+
+// SameAsPlannedActionID is the action of the button answering with the
+// plan of the previous standup.
+const SameAsPlannedActionID = "answer_same_as_planned"
+
+// SameAsYesterdayActionID is the action of the button answering with the
+// previous answer to the same question.
+const SameAsYesterdayActionID = "answer_same_as_yesterday"
+
+// questionMessage is the DM asking a question, with a button to answer the
+// same as last time. The question the plan of the day is carried over to
+// also comes with the previous plan and a button to answer with it.
+func questionMessage(db *dynamo.DB, s *standup.Standup, index int) (slackapi.Message, error) {
+	text := Title(s, index)
+	msg := slackapi.Message{Text: text}
+
+	previous, err := s.Previous(db)
+	if err != nil {
+		return msg, err
+	}
+
+	blocks := []interface{}{slackapi.Section(text)}
+	var buttons []interface{}
+	value := summary.ActionValue(s.UserID, s.Date, index)
+
+	st, err := setting.Get(db, s.TargetChannelID)
+	if err != nil && err != dynamo.ErrNotFound {
+		return msg, err
+	}
+	if err == nil {
+		if plan, recap, ok := st.CarryOver(); ok && recap == index {
+			if planned := standup.LastAnswerAt(previous, plan); planned != "" && !summary.IsNone(planned) {
+				blocks = append(blocks, slackapi.Context(slackapi.Markdown("Yesterday you planned:\n"+quote(planned))))
+				buttons = append(buttons, slackapi.Button("Same as planned", SameAsPlannedActionID, value))
+			}
+		}
+	}
+
+	if standup.LastAnswerTo(previous, s.Questions[index].Text) != "" {
+		buttons = append(buttons, slackapi.Button("Same as yesterday", SameAsYesterdayActionID, value))
+	}
+
+	if len(buttons) == 0 {
+		return msg, nil
+	}
+
+	b, err := json.Marshal(append(blocks, slackapi.Actions(buttons...)))
+	if err != nil {
+		return msg, err
+	}
+	msg.Blocks = json.RawMessage(b)
+
+	return msg, nil
+}
+
+// mentionNames returns the names of users mentioned in answers. Users who can't be
+// looked up are left out, and shown by ID.
+func mentionNames(ctx context.Context, db *dynamo.DB, cl slackapi.Client, userIDs []string) map[string]string {
+	names := map[string]string{}
+	for _, userID := range userIDs {
+		u, err := usercache.Get(ctx, db, cl, userID)
+		if err != nil {
+			log.Printf("failed to get mentioned user %s: %s", userID, err)
+			continue
+		}
+		names[userID] = u.RealName
+	}
+
+	return names
+}
+
+// notifyMentions sends the users mentioned in the answers of a member a link
+// to the summary.
+func notifyMentions(ctx context.Context, cl slackapi.Client, s *standup.Standup, mentions []string) error {
+	var userIDs []string
+	for _, userID := range mentions {
+		if userID != s.UserID {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	permalink, err := cl.GetPermalink(ctx, s.TargetChannelID, s.SummaryTimestamp(s.TargetChannelID))
+	if err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("<@%s> mentioned you in their stand-up. <%s|See the summary>", s.UserID, permalink)
+
+	var errs util.Errors
+	for _, userID := range userIDs {
+		if _, err := cl.PostMessage(ctx, userID, slackapi.Message{Text: text}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.Err()
+}
+
+// Title is a question with the progress of the standup, e.g.
+// "Question 2/3: What will you do today?".
+func Title(s *standup.Standup, index int) string {
+	q := s.Questions[index]
+
+	text := q.Text
+	if q.FollowUp != "" {
+		text = q.FollowUp
+	}
+
+	return fmt.Sprintf("Question %d/%d: %s", index+1, len(s.Questions), text)
+}
+
+// confirm tells the member that the standup is done, with a link to its
+// summary.
+func confirm(ctx context.Context, cl slackapi.Client, s *standup.Standup) error {
+	text := "Thanks, your stand-up is done!"
+
+	permalink, err := cl.GetPermalink(ctx, s.TargetChannelID, s.SummaryTimestamp(s.TargetChannelID))
+	if err != nil {
+		log.Printf("failed to get the permalink of the summary of %s: %s", s.UserID, err)
+	} else {
+		text += fmt.Sprintf(" <%s|See the summary>", permalink)
+	}
+
+	_, err = cl.PostMessage(ctx, s.UserID, slackapi.Message{Text: text})
+	return err
+}
+
+func quote(text string) string {
+	return "> " + strings.Replace(text, "\n", "\n> ", -1)
+}
+
+// trackBlocker keeps the blocker of the member up to date with the answer to
+// the blocker question. An answer confirming the open blocker is summarized
+// as the blocker itself.
+func trackBlocker(db *dynamo.DB, st *setting.Setting, s *standup.Standup, data *summary.Data) error {
+	if !st.TracksBlockers() {
+		return nil
+	}
+
+	index := st.BlockerIndex()
+	if index < 0 || index >= len(s.Answers) {
+		return nil
+	}
+	answer := s.Answers[index].FullText()
+
+	b, err := blocker.OpenOf(db, s.TargetChannelID, s.UserID)
+	if err != nil && err != dynamo.ErrNotFound {
+		return err
+	}
+
+	switch {
+	case summary.IsNone(answer):
+		if b == nil || b.FirstSeen > s.Date {
+			return nil
+		}
+
+		return b.Resolve(db, s.UserID)
+	case blocker.Still(answer):
+		if b == nil {
+			return nil
+		}
+
+		for i := range data.Items {
+			if data.Items[i].Index == index {
+				data.Items[i].Answer = b.Text
+			}
+		}
+
+		return b.Continue(db, s.Date)
+	default:
+		_, err := blocker.Report(db, s.TargetChannelID, s.UserID, s.Date, answer)
+		return err
+	}
+}
+
+// escalate posts the blocker of the member to the escalation channel of
+// the setting, or updates it after the answer was edited.
+func escalate(ctx context.Context, db *dynamo.DB, cl slackapi.Client, st *setting.Setting, s *standup.Standup, data summary.Data) error {
+	if e := s.Escalation; e != nil {
+		return skipUnreachable(e.ChannelID, cl.UpdateMessage(ctx, e.ChannelID, e.Timestamp, summary.Escalation(data, e.ResolvedBy, e.ResolvedAt)))
+	}
+
+	if st.EscalationChannelID == "" || data.Blocker() == nil {
+		return nil
+	}
+
+	channelID := st.EscalationChannelID
+	if isUser(channelID) {
+		var err error
+		if channelID, err = cl.OpenDM(ctx, st.EscalationChannelID); err != nil {
+			return skipUnreachable(st.EscalationChannelID, err)
+		}
+	}
+
+	ts, err := cl.PostMessage(ctx, channelID, summary.Escalation(data, "", ""))
+	if err != nil {
+		return skipUnreachable(channelID, err)
+	}
+
+	return s.Escalate(db, channelID, ts)
+}
+
+// UpdateEscalation updates the escalation message of a standup, e.g. once
+// its blocker is resolved while the standup waits for an answer again.
+func UpdateEscalation(ctx context.Context, db *dynamo.DB, botcl slackapi.Client, cl slackapi.Client, s *standup.Standup) error {
+	e := s.Escalation
+	if e == nil {
+		return nil
+	}
+
+	u, err := usercache.Get(ctx, db, cl, s.UserID)
+	if err != nil {
+		return err
+	}
+
+	st, err := setting.Get(db, s.TargetChannelID)
+	if err == dynamo.ErrNotFound {
+		st = &setting.Setting{TargetChannelID: s.TargetChannelID}
+	} else if err != nil {
+		return err
+	}
+
+	data := summary.NewData(s, u)
+	data.MarkBlocker(st.BlockerIndex())
+	data.Resolve(mentionNames(ctx, db, cl, data.Mentions()))
+
+	return skipUnreachable(e.ChannelID, botcl.UpdateMessage(ctx, e.ChannelID, e.Timestamp, summary.Escalation(data, e.ResolvedBy, e.ResolvedAt)))
+}
+
+// render renders the summary of a destination, and reports whether there
+// is anything to post. A template which fails on this standup falls back to
+// the default layout rather than losing the summary.
+func render(st *setting.Setting, format string, data summary.Data) (slackapi.Message, bool) {
+	switch format {
+	case setting.FormatBlockers:
+		return summary.Blockers(data)
+	case setting.FormatCompact:
+		return summary.Compact(data), true
+	}
+
+	msg, err := summary.Render(st.SummaryTemplate, data)
+	if err != nil {
+		log.Printf("failed to render the summary template of %s: %s", st.TargetChannelID, err)
+		return summary.Default(data), true
+	}
+
+	return msg, true
+}
+
+// isUser reports whether a destination is a user to DM.
+func isUser(id string) bool {
+	return strings.HasPrefix(id, "U") || strings.HasPrefix(id, "W")
+}
+
+// updateThread counts a finished member in the parent message of the day.
+func updateThread(ctx context.Context, db *dynamo.DB, cl slackapi.Client, s *standup.Standup) error {
+	t, err := thread.MarkDone(db, s.TargetChannelID, s.Date, s.UserID)
+	if err != nil {
+		return err
+	}
+
+	return cl.UpdateMessage(ctx, s.TargetChannelID, t.Timestamp, slackapi.Message{Text: t.Text()})
+}
@@ -0,0 +1,393 @@
+package questions
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/summary"
+	"github.com/tsub/serverless-daily-standup-bot/internal/usercache"
+)
+
+type mockedDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	Previous []standup.Standup
+	Setting  *setting.Setting
+}
+
+func (m *mockedDynamo) PutItemWithContext(context aws.Context, input *dynamodb.PutItemInput, options ...request.Option) (*dynamodb.PutItemOutput, error) {
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// QueryWithContext finds the previous standups, none by default.
+func (m *mockedDynamo) QueryWithContext(context aws.Context, input *dynamodb.QueryInput, options ...request.Option) (*dynamodb.QueryOutput, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, s := range m.Previous {
+		item, err := dynamo.MarshalItem(s)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return &dynamodb.QueryOutput{Items: items, Count: aws.Int64(int64(len(items)))}, nil
+}
+
+// GetItemWithContext finds the setting, none by default, and no users.
+func (m *mockedDynamo) GetItemWithContext(context aws.Context, input *dynamodb.GetItemInput, options ...request.Option) (*dynamodb.GetItemOutput, error) {
+	if _, ok := input.Key["target_channel_id"]; !ok || m.Setting == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+
+	item, err := dynamo.MarshalItem(m.Setting)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+// UpdateItemWithContext serves the thread of the day, with nobody done.
+func (m *mockedDynamo) UpdateItemWithContext(context aws.Context, input *dynamodb.UpdateItemInput, options ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	return &dynamodb.UpdateItemOutput{Attributes: map[string]*dynamodb.AttributeValue{
+		"target_channel_id": &dynamodb.AttributeValue{S: aws.String("C1")},
+		"date":              &dynamodb.AttributeValue{S: aws.String("2019-01-01")},
+		"ts":                &dynamodb.AttributeValue{S: aws.String("0.5")},
+		"user_ids":          &dynamodb.AttributeValue{SS: []*string{aws.String("U1"), aws.String("U3")}},
+	}}, nil
+}
+
+// failingClient fails to post to some channels, or to post some texts,
+// with the error code they map to.
+type failingClient struct {
+	*slackapi.Fake
+	fail map[string]string
+}
+
+func (c *failingClient) PostMessage(ctx context.Context, channelID string, msg slackapi.Message) (string, error) {
+	if code, ok := c.fail[channelID]; ok {
+		return "", &slackapi.Error{Method: "chat.postMessage", Code: code}
+	}
+	if code, ok := c.fail[msg.Text]; ok {
+		return "", &slackapi.Error{Method: "chat.postMessage", Code: code}
+	}
+
+	return c.Fake.PostMessage(ctx, channelID, msg)
+}
+
+func testSetting() *setting.Setting {
+	return &setting.Setting{
+		TargetChannelID: "C1",
+		Questions:       []string{"What did you do yesterday?", "What will you do today?"},
+		Destinations: []setting.Destination{
+			setting.Destination{ChannelID: "C2", Format: setting.FormatCompact},
+			setting.Destination{ChannelID: "U2", Format: setting.FormatFull},
+		},
+	}
+}
+
+func testStandup(summaries []standup.Summary) *standup.Standup {
+	return &standup.Standup{
+		UserID:          "U1",
+		Date:            "2019-01-01",
+		TargetChannelID: "C1",
+		Status:          standup.StatusCompleted,
+		Questions: []standup.Question{
+			standup.Question{Text: "What did you do yesterday?", PostedAt: "1.0"},
+			standup.Question{Text: "What will you do today?", PostedAt: "2.0"},
+		},
+		Answers: []standup.Answer{
+			standup.Answer{Text: "Reviews", PostedAt: "1.5"},
+			standup.Answer{Text: "Docs", PostedAt: "2.5"},
+		},
+		Summaries: summaries,
+	}
+}
+
+func channels(messages []slackapi.FakeMessage) []string {
+	var ids []string
+	for _, m := range messages {
+		ids = append(ids, m.ChannelID)
+	}
+
+	return ids
+}
+
+func TestPublish(t *testing.T) {
+	posted := []standup.Summary{
+		standup.Summary{ChannelID: "C1", Timestamp: "3.0"},
+		standup.Summary{ChannelID: "C2", Timestamp: "3.1"},
+		standup.Summary{ChannelID: "DU2", Timestamp: "3.2"},
+	}
+
+	tests := []struct {
+		name      string
+		summaries []standup.Summary
+		// fails maps the channels failing on each attempt to their error
+		fails       []map[string]string
+		wantPosted  []string
+		wantUpdated []string
+	}{
+		{
+			name:       "posts to every destination",
+			fails:      []map[string]string{nil},
+			wantPosted: []string{"C1", "C2", "DU2"},
+		},
+		{
+			name:        "updates every destination after an edit",
+			summaries:   posted,
+			fails:       []map[string]string{nil},
+			wantUpdated: []string{"C1", "C2", "DU2"},
+		},
+		{
+			name:       "retries only the failed destination",
+			fails:      []map[string]string{{"C2": "fatal_error"}, nil},
+			wantPosted: []string{"C1", "DU2", "C2"},
+			// The summaries posted on the first attempt are updated
+			wantUpdated: []string{"C1", "DU2"},
+		},
+		{
+			name:       "skips a destination the bot isn't in",
+			fails:      []map[string]string{{"C2": "not_in_channel"}},
+			wantPosted: []string{"C1", "DU2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dynamo.NewFromIface(&mockedDynamo{})
+			cl := &failingClient{Fake: &slackapi.Fake{}}
+			s := testStandup(append([]standup.Summary(nil), tt.summaries...))
+			data := summary.NewData(s, &usercache.User{RealName: "Alice"})
+
+			for i, fail := range tt.fails {
+				cl.fail = fail
+
+				_, err := publish(context.Background(), db, cl, testSetting(), s, data)
+				transient := false
+				for _, code := range fail {
+					transient = transient || code == "fatal_error"
+				}
+				if transient && err == nil {
+					t.Fatalf("Want an error on attempt %d", i+1)
+				}
+				if !transient && err != nil {
+					t.Fatalf("%q", err)
+				}
+			}
+
+			if got := channels(cl.Posted); !reflect.DeepEqual(got, tt.wantPosted) {
+				t.Fatalf("Want posts to %v, got %v", tt.wantPosted, got)
+			}
+			if got := channels(cl.Updated); !reflect.DeepEqual(got, tt.wantUpdated) {
+				t.Fatalf("Want updates of %v, got %v", tt.wantUpdated, got)
+			}
+			if len(s.Summaries) != len(tt.wantPosted)+len(tt.summaries) {
+				t.Fatalf("Unexpected summaries recorded: %+v", s.Summaries)
+			}
+		})
+	}
+}
+
+func TestRetract(t *testing.T) {
+	db := dynamo.NewFromIface(&mockedDynamo{})
+	cl := &slackapi.Fake{}
+
+	s := testStandup([]standup.Summary{
+		standup.Summary{ChannelID: "C1", Timestamp: "3.0"},
+		standup.Summary{ChannelID: "DU2", Timestamp: "3.2"},
+	})
+	s.FinishedAt = "3.0"
+	s.ThreadTS = "0.5"
+	s.Status = standup.StatusAsking
+	s.Answers = []standup.Answer{standup.Answer{}, standup.Answer{}}
+
+	if err := retract(context.Background(), db, cl, s); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if got := channels(cl.Deleted); !reflect.DeepEqual(got, []string{"C1", "DU2"}) {
+		t.Fatalf("Want the summaries to be deleted, got %v", got)
+	}
+	if len(s.Summaries) != 0 || s.FinishedAt != "" {
+		t.Fatalf("Unexpected standup: %+v", s)
+	}
+
+	if len(cl.Updated) != 1 || cl.Updated[0].Timestamp != "0.5" || !strings.HasSuffix(cl.Updated[0].Message.Text, "0/2 done") {
+		t.Fatalf("Want the thread to be updated, got %+v", cl.Updated)
+	}
+}
+
+func TestSendNextQuestionIntroducesOnce(t *testing.T) {
+	db := dynamo.NewFromIface(&mockedDynamo{})
+	cl := &failingClient{Fake: &slackapi.Fake{}}
+
+	s := testStandup(nil)
+	s.Status = standup.StatusPending
+	s.Answers = nil
+	for i := range s.Questions {
+		s.Questions[i].PostedAt = ""
+	}
+
+	question := Title(s, 0)
+
+	// The question fails after the intro, then goes through on a retry
+	cl.fail = map[string]string{question: "fatal_error"}
+	if err := sendNextQuestion(context.Background(), db, cl, s); err == nil {
+		t.Fatal("Want an error for the question")
+	}
+	cl.fail = nil
+	if err := sendNextQuestion(context.Background(), db, cl, s); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(cl.Posted) != 2 {
+		t.Fatalf("Want an intro and a question, got %+v", cl.Posted)
+	}
+	if text := cl.Posted[0].Message.Text; !strings.Contains(text, "stand-up of <#C1>, 2 questions") || !strings.Contains(text, "`skip`") {
+		t.Fatalf("Unexpected intro %q", text)
+	}
+	if cl.Posted[1].Message.Text != question || !s.Introduced || s.Questions[0].PostedAt == "" {
+		t.Fatalf("Unexpected standup: %+v", s)
+	}
+}
+
+func TestSendNextQuestionSkipsIntroOfBackfill(t *testing.T) {
+	db := dynamo.NewFromIface(&mockedDynamo{})
+	cl := &slackapi.Fake{}
+
+	s := testStandup(nil)
+	s.Status = standup.StatusPending
+	s.Late = true
+	s.Answers = nil
+	s.Questions[0].PostedAt = ""
+
+	if err := sendNextQuestion(context.Background(), db, cl, s); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(cl.Posted) != 1 || s.Introduced {
+		t.Fatalf("Want only the question, got %+v", cl.Posted)
+	}
+}
+
+func TestEscalateSkipsUnreachableChannel(t *testing.T) {
+	db := dynamo.NewFromIface(&mockedDynamo{})
+	cl := &failingClient{Fake: &slackapi.Fake{}, fail: map[string]string{"C9": "channel_not_found"}}
+
+	st := testSetting()
+	st.EscalationChannelID = "C9"
+	s := testStandup(nil)
+	data := summary.NewData(s, &usercache.User{RealName: "Alice"})
+	data.MarkBlocker(1)
+
+	if err := escalate(context.Background(), db, cl, st, s, data); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if s.Escalation != nil {
+		t.Fatalf("Want no escalation recorded, got %+v", s.Escalation)
+	}
+}
+
+func TestUpdateEscalation(t *testing.T) {
+	db := dynamo.NewFromIface(&mockedDynamo{})
+	cl := &slackapi.Fake{Users: map[string]slackapi.User{"U1": {ID: "U1"}}}
+
+	// Resolved while the standup is back to asking
+	s := testStandup(nil)
+	s.Status = standup.StatusAsking
+	s.Escalation = &standup.Escalation{ChannelID: "C9", Timestamp: "4.0", ResolvedBy: "U2", ResolvedAt: "2019-01-01T10:00:00Z"}
+
+	if err := UpdateEscalation(context.Background(), db, cl, cl, s); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(cl.Updated) != 1 || cl.Updated[0].ChannelID != "C9" || cl.Updated[0].Timestamp != "4.0" {
+		t.Fatalf("Want the escalation message to be updated, got %+v", cl.Updated)
+	}
+}
+
+// carryOverDynamo serves a setting carrying the answer to the second
+// question over to the first one, and yesterday's standup planning plan.
+func carryOverDynamo(plan string) *mockedDynamo {
+	st := testSetting()
+	st.PlanQuestion = 2
+	st.RecapQuestion = 1
+
+	yesterday := testStandup(nil)
+	yesterday.Date = "2018-12-31"
+	yesterday.Answers[1].Text = plan
+
+	return &mockedDynamo{Previous: []standup.Standup{*yesterday}, Setting: st}
+}
+
+func TestQuestionMessageCarriesOverPlan(t *testing.T) {
+	db := dynamo.NewFromIface(carryOverDynamo("Docs\nTests"))
+
+	s := testStandup(nil)
+	s.Status = standup.StatusAsking
+	s.Answers = nil
+
+	msg, err := questionMessage(db, s, 0)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	blocks := string(msg.Blocks)
+	if !strings.Contains(blocks, `Yesterday you planned:\n\u003e Docs\n\u003e Tests`) {
+		t.Fatalf("Want the plan to be quoted, got %s", blocks)
+	}
+	if !strings.Contains(blocks, SameAsPlannedActionID) || !strings.Contains(blocks, SameAsYesterdayActionID) {
+		t.Fatalf("Want both buttons, got %s", blocks)
+	}
+
+	// Only the recap question carries the plan over
+	msg, err = questionMessage(db, s, 1)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+	if blocks := string(msg.Blocks); strings.Contains(blocks, "planned") || !strings.Contains(blocks, SameAsYesterdayActionID) {
+		t.Fatalf("Want only the same as yesterday button, got %s", blocks)
+	}
+}
+
+func TestQuestionMessageSkipsEmptyPlan(t *testing.T) {
+	db := dynamo.NewFromIface(carryOverDynamo("none"))
+
+	s := testStandup(nil)
+	s.Status = standup.StatusAsking
+	s.Answers = nil
+
+	msg, err := questionMessage(db, s, 0)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if blocks := string(msg.Blocks); strings.Contains(blocks, "planned") || strings.Contains(blocks, SameAsPlannedActionID) {
+		t.Fatalf("Want no plan carried over, got %s", blocks)
+	}
+}
+
+func TestQuestionMessageWithoutPrevious(t *testing.T) {
+	db := dynamo.NewFromIface(&mockedDynamo{})
+
+	s := testStandup(nil)
+	msg, err := questionMessage(db, s, 0)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if msg.Text != Title(s, 0) || msg.Blocks != nil {
+		t.Fatalf("Want a plain question, got %+v", msg)
+	}
+}
@@ -0,0 +1,101 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
+)
+
+var startFunctionArn = os.Getenv("START_FUNCTION_ARN")
+var resourcePrefix = os.Getenv("RESOURCE_PREFIX")
+
+// CloudWatch schedules the start function with one CloudWatch Events rule
+// per setting.
+type CloudWatch struct {
+	client cloudwatcheventsiface.CloudWatchEventsAPI
+}
+
+func NewCloudWatch(p client.ConfigProvider) *CloudWatch {
+	return &CloudWatch{client: cloudwatchevents.New(p)}
+}
+
+func ruleName(teamID string, targetChannelID string) string {
+	return fmt.Sprintf("%s-%s-%s", resourcePrefix, teamID, targetChannelID)
+}
+
+func (c *CloudWatch) Get(ctx context.Context, teamID string, targetChannelID string) (string, error) {
+	describeRuleInput := &cloudwatchevents.DescribeRuleInput{
+		Name: aws.String(ruleName(teamID, targetChannelID)),
+	}
+
+	resp, err := c.client.DescribeRuleWithContext(ctx, describeRuleInput)
+	if err != nil {
+		// Skip if you haven't set rule yet
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudwatchevents.ErrCodeResourceNotFoundException {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return aws.StringValue(resp.ScheduleExpression), nil
+}
+
+func (c *CloudWatch) Put(ctx context.Context, teamID string, targetChannelID string, expr string) error {
+	name := ruleName(teamID, targetChannelID)
+
+	putRuleInput := &cloudwatchevents.PutRuleInput{
+		Name:               aws.String(name),
+		ScheduleExpression: aws.String(expr),
+		State:              aws.String(cloudwatchevents.RuleStateEnabled),
+	}
+	_, err := c.client.PutRuleWithContext(ctx, putRuleInput)
+	if err != nil {
+		return err
+	}
+
+	input, err := json.Marshal(Input{TeamID: teamID, TargetChannelID: targetChannelID})
+	if err != nil {
+		return err
+	}
+
+	putTargetsInput := &cloudwatchevents.PutTargetsInput{
+		Rule: aws.String(name),
+		Targets: []*cloudwatchevents.Target{
+			&cloudwatchevents.Target{
+				Id:    aws.String("1"),
+				Arn:   aws.String(startFunctionArn),
+				Input: aws.String(string(input[:])),
+			},
+		},
+	}
+	_, err = c.client.PutTargetsWithContext(ctx, putTargetsInput)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *CloudWatch) Disable(ctx context.Context, teamID string, targetChannelID string) error {
+	disableRuleInput := &cloudwatchevents.DisableRuleInput{
+		Name: aws.String(ruleName(teamID, targetChannelID)),
+	}
+
+	_, err := c.client.DisableRuleWithContext(ctx, disableRuleInput)
+	if err != nil {
+		// Nothing to disable if the rule was never created
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudwatchevents.ErrCodeResourceNotFoundException {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expression is a parsed CloudWatch Events schedule expression, evaluated
+// in UTC like CloudWatch does.
+// see https://docs.aws.amazon.com/AmazonCloudWatch/latest/events/ScheduledEvents.html
+type Expression interface {
+	// Match reports whether the schedule fires in the minute of t.
+	Match(t time.Time) bool
+}
+
+func Parse(expr string) (Expression, error) {
+	expr = strings.TrimSpace(expr)
+
+	switch {
+	case strings.HasPrefix(expr, "cron(") && strings.HasSuffix(expr, ")"):
+		return parseCron(expr[len("cron(") : len(expr)-1])
+	case strings.HasPrefix(expr, "rate(") && strings.HasSuffix(expr, ")"):
+		return parseRate(expr[len("rate(") : len(expr)-1])
+	default:
+		return nil, fmt.Errorf("schedule expression must be cron(...) or rate(...): %q", expr)
+	}
+}
+
+type rate struct {
+	every time.Duration
+}
+
+func parseRate(s string) (Expression, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid rate expression: %q", s)
+	}
+
+	value, err := strconv.Atoi(fields[0])
+	if err != nil || value < 1 {
+		return nil, fmt.Errorf("invalid rate value: %q", fields[0])
+	}
+
+	var unit time.Duration
+	switch strings.TrimSuffix(fields[1], "s") {
+	case "minute":
+		unit = time.Minute
+	case "hour":
+		unit = time.Hour
+	case "day":
+		unit = 24 * time.Hour
+	default:
+		return nil, fmt.Errorf("invalid rate unit: %q", fields[1])
+	}
+
+	return rate{every: time.Duration(value) * unit}, nil
+}
+
+// Match counts from the Unix epoch, since a rate has no fixed start.
+func (r rate) Match(t time.Time) bool {
+	minutes := t.Unix() / 60
+	return minutes%int64(r.every/time.Minute) == 0
+}
+
+type cron struct {
+	minutes     field
+	hours       field
+	daysOfMonth field
+	months      field
+	daysOfWeek  field
+	years       field
+}
+
+// field is the set of allowed values, nil when it is "?".
+type field map[int]bool
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+// Days of week start from 1 on Sunday in CloudWatch.
+var dayNames = map[string]int{
+	"SUN": 1, "MON": 2, "TUE": 3, "WED": 4, "THU": 5, "FRI": 6, "SAT": 7,
+}
+
+func parseCron(s string) (Expression, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("cron expression needs 6 fields: %q", s)
+	}
+
+	var c cron
+	var err error
+
+	if c.minutes, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if c.hours, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if c.daysOfMonth, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if c.months, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if c.daysOfWeek, err = parseField(fields[4], 1, 7, dayNames); err != nil {
+		return nil, err
+	}
+	if c.years, err = parseField(fields[5], 1970, 2199, nil); err != nil {
+		return nil, err
+	}
+
+	if (c.daysOfMonth == nil) == (c.daysOfWeek == nil) {
+		return nil, fmt.Errorf("one of day-of-month and day-of-week must be ?: %q", s)
+	}
+	if c.minutes == nil || c.hours == nil || c.months == nil || c.years == nil {
+		return nil, fmt.Errorf("? is only allowed in day-of-month and day-of-week: %q", s)
+	}
+
+	return c, nil
+}
+
+func parseField(s string, min int, max int, names map[string]int) (field, error) {
+	if s == "?" {
+		return nil, nil
+	}
+
+	f := field{}
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid step: %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		from, to := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			if from, err = parseValue(bounds[0], min, max, names); err != nil {
+				return nil, err
+			}
+			if to, err = parseValue(bounds[1], min, max, names); err != nil {
+				return nil, err
+			}
+		default:
+			v, err := parseValue(part, min, max, names)
+			if err != nil {
+				return nil, err
+			}
+
+			from = v
+			if step == 1 {
+				to = v
+			}
+		}
+
+		for v := from; v <= to; v += step {
+			f[v] = true
+		}
+	}
+
+	return f, nil
+}
+
+func parseValue(s string, min int, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported value: %q", s)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value out of range %d-%d: %d", min, max, v)
+	}
+
+	return v, nil
+}
+
+func (c cron) Match(t time.Time) bool {
+	t = t.UTC()
+
+	if !c.minutes[t.Minute()] || !c.hours[t.Hour()] || !c.months[int(t.Month())] || !c.years[t.Year()] {
+		return false
+	}
+
+	if c.daysOfMonth != nil {
+		return c.daysOfMonth[t.Day()]
+	}
+
+	return c.daysOfWeek[int(t.Weekday())+1]
+}
@@ -0,0 +1,80 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronSuccess(t *testing.T) {
+	expr, err := Parse("cron(0 1 ? * MON-FRI *)")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	cases := []struct {
+		at   string
+		want bool
+	}{
+		{"2018-09-03T01:00:00Z", true},  // Monday
+		{"2018-09-07T01:00:59Z", true},  // Friday
+		{"2018-09-08T01:00:00Z", false}, // Saturday
+		{"2018-09-03T01:01:00Z", false},
+		{"2018-09-03T02:00:00Z", false},
+	}
+
+	for _, c := range cases {
+		at, _ := time.Parse(time.RFC3339, c.at)
+		if got := expr.Match(at); got != c.want {
+			t.Errorf("Match(%s): want %v, got %v", c.at, c.want, got)
+		}
+	}
+}
+
+func TestParseCronListsAndSteps(t *testing.T) {
+	expr, err := Parse("cron(0/15 9,18 1 JAN-MAR ? 2018-2019)")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2019-02-01T18:45:00Z")
+	if !expr.Match(at) {
+		t.Fatalf("Want %s to match", at)
+	}
+
+	at, _ = time.Parse(time.RFC3339, "2019-04-01T18:45:00Z")
+	if expr.Match(at) {
+		t.Fatalf("Want %s not to match", at)
+	}
+}
+
+func TestParseRateSuccess(t *testing.T) {
+	expr, err := Parse("rate(5 minutes)")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2018-09-03T01:05:00Z")
+	if !expr.Match(at) {
+		t.Fatalf("Want %s to match", at)
+	}
+
+	if expr.Match(at.Add(time.Minute)) {
+		t.Fatalf("Want %s not to match", at.Add(time.Minute))
+	}
+}
+
+func TestParseFailure(t *testing.T) {
+	for _, expr := range []string{
+		"every day",
+		"cron(0 1 * * MON-FRI *)",
+		"cron(0 1 ? * MON-FRI)",
+		"cron(0 25 ? * MON-FRI *)",
+		"cron(0 1 L * ? *)",
+		"rate(0 minutes)",
+		"rate(5 weeks)",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Want an error for %q", expr)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package schedule
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+)
+
+// Local keeps schedules in the settings table and fires them from the
+// current process, for running without CloudWatch Events.
+type Local struct {
+	db *dynamo.DB
+}
+
+func NewLocal(db *dynamo.DB) *Local {
+	return &Local{db: db}
+}
+
+func (l *Local) Get(ctx context.Context, teamID string, targetChannelID string) (string, error) {
+	s, err := setting.Get(l.db, targetChannelID)
+	if err == dynamo.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return s.ScheduleExpression, nil
+}
+
+func (l *Local) Put(ctx context.Context, teamID string, targetChannelID string, expr string) error {
+	if _, err := Parse(expr); err != nil {
+		return err
+	}
+
+	return setting.SetSchedule(l.db, targetChannelID, teamID, expr)
+}
+
+// Disable is a no-op, Due already skips disabled settings.
+func (l *Local) Disable(ctx context.Context, teamID string, targetChannelID string) error {
+	return nil
+}
+
+// Run calls start for every setting whose schedule matches the current
+// minute, until ctx is canceled.
+func (l *Local) Run(ctx context.Context, start func(ctx context.Context, input Input) error) {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+
+		settings, err := setting.All(l.db)
+		if err != nil {
+			log.Printf("failed to load settings: %s", err)
+			continue
+		}
+
+		for _, s := range Due(settings, next) {
+			go func(input Input) {
+				if err := start(ctx, input); err != nil {
+					log.Printf("failed to start %s: %s", input.TargetChannelID, err)
+				}
+			}(Input{TeamID: s.TeamID, TargetChannelID: s.TargetChannelID})
+		}
+	}
+}
+
+// Due returns the settings scheduled in the minute of t.
+func Due(settings []setting.Setting, t time.Time) []setting.Setting {
+	var due []setting.Setting
+
+	for _, s := range settings {
+		if s.ScheduleExpression == "" || !s.Active() {
+			continue
+		}
+
+		expr, err := Parse(s.ScheduleExpression)
+		if err != nil {
+			log.Printf("invalid schedule of %s: %s", s.TargetChannelID, err)
+			continue
+		}
+
+		if expr.Match(t) {
+			due = append(due, s)
+		}
+	}
+
+	return due
+}
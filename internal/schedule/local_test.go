@@ -0,0 +1,26 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+)
+
+func TestDueSuccess(t *testing.T) {
+	settings := []setting.Setting{
+		setting.Setting{TargetChannelID: "weekdays", ScheduleExpression: "cron(0 1 ? * MON-FRI *)"},
+		setting.Setting{TargetChannelID: "weekends", ScheduleExpression: "cron(0 1 ? * SAT,SUN *)"},
+		setting.Setting{TargetChannelID: "broken", ScheduleExpression: "every day"},
+		setting.Setting{TargetChannelID: "unscheduled"},
+		setting.Setting{TargetChannelID: "archived", ScheduleExpression: "cron(0 1 ? * MON-FRI *)", Paused: true},
+		setting.Setting{TargetChannelID: "uninstalled", ScheduleExpression: "cron(0 1 ? * MON-FRI *)", Disabled: true},
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2018-09-03T01:00:00Z")
+	due := Due(settings, at)
+
+	if len(due) != 1 || due[0].TargetChannelID != "weekdays" {
+		t.Fatalf("Want only weekdays to be due, got %+v", due)
+	}
+}
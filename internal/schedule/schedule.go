@@ -0,0 +1,50 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Scheduler keeps the execution schedule of the start function for each
+// setting.
+type Scheduler interface {
+	// Get returns the expression scheduled for a channel, or "" if none.
+	Get(ctx context.Context, teamID string, targetChannelID string) (string, error)
+	Put(ctx context.Context, teamID string, targetChannelID string, expr string) error
+	// Disable stops firing a schedule until it is Put again.
+	Disable(ctx context.Context, teamID string, targetChannelID string) error
+}
+
+// Input is what the start function receives when a schedule fires.
+type Input struct {
+	TeamID          string `json:"team_id"`
+	TargetChannelID string `json:"target_channel_id"`
+}
+
+var (
+	mu      sync.Mutex
+	current Scheduler
+)
+
+// Use replaces the scheduler returned by Current.
+func Use(s Scheduler) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	current = s
+}
+
+// Current returns the scheduler in use, CloudWatch Events unless Use has
+// been called.
+func Current() Scheduler {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if current == nil {
+		current = NewCloudWatch(session.New())
+	}
+
+	return current
+}
@@ -0,0 +1,54 @@
+package setting
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CarryOver returns the indexes of the question asking for the plan of the
+// day and of the question asked the next day about what was done, or false
+// if they aren't linked.
+func (s *Setting) CarryOver() (plan int, recap int, ok bool) {
+	plan, recap = s.PlanQuestion-1, s.RecapQuestion-1
+	if plan < 0 || recap < 0 || plan >= len(s.Questions) || recap >= len(s.Questions) {
+		return 0, 0, false
+	}
+
+	return plan, recap, true
+}
+
+// ParseCarryOver reads the number of the plan question followed by the
+// number of the question it is carried over to, e.g. "2 1".
+func ParseCarryOver(text string, questions int) (int, int, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return 0, 0, nil
+	}
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("%q should be two question numbers", strings.TrimSpace(text))
+	}
+
+	numbers := make([]int, 2)
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > questions {
+			return 0, 0, fmt.Errorf("%q isn't the number of a question", field)
+		}
+		numbers[i] = n
+	}
+	if numbers[0] == numbers[1] {
+		return 0, 0, fmt.Errorf("%q links a question to itself", strings.TrimSpace(text))
+	}
+
+	return numbers[0], numbers[1], nil
+}
+
+// FormatCarryOver is the reverse of ParseCarryOver.
+func FormatCarryOver(plan int, recap int) string {
+	if plan == 0 || recap == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%d %d", plan, recap)
+}
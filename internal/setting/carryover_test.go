@@ -0,0 +1,31 @@
+package setting
+
+import "testing"
+
+func TestParseCarryOver(t *testing.T) {
+	plan, recap, err := ParseCarryOver(" 2 1 ", 3)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+	if plan != 2 || recap != 1 {
+		t.Fatalf("Unexpected %d %d", plan, recap)
+	}
+
+	for _, text := range []string{"2", "2 2", "2 4", "a 1", "1 2 3"} {
+		if _, _, err := ParseCarryOver(text, 3); err == nil {
+			t.Fatalf("Want an error for %q", text)
+		}
+	}
+}
+
+func TestCarryOver(t *testing.T) {
+	s := &Setting{Questions: []string{"Yesterday?", "Today?"}, PlanQuestion: 2, RecapQuestion: 1}
+	if plan, recap, ok := s.CarryOver(); !ok || plan != 1 || recap != 0 {
+		t.Fatalf("Unexpected %d %d %t", plan, recap, ok)
+	}
+
+	s.Questions = s.Questions[:1]
+	if _, _, ok := s.CarryOver(); ok {
+		t.Fatal("Want no carry over when a question was removed")
+	}
+}
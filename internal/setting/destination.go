@@ -0,0 +1,137 @@
+package setting
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Formats of the summaries posted to a destination.
+const (
+	// FormatFull is the summary rendered with the template of the setting.
+	FormatFull = "full"
+	// FormatBlockers only has the answer to the blockers question.
+	FormatBlockers = "blockers"
+	// FormatCompact lists every answer in a single section.
+	FormatCompact = "compact"
+)
+
+// Destination is where summaries are posted besides the target channel,
+// a channel or a user ID for a DM.
+type Destination struct {
+	ChannelID string `dynamo:"channel_id"`
+	Format    string `dynamo:"format"`
+}
+
+// AllDestinations returns the target channel, with the full format, and
+// the other destinations.
+func (s *Setting) AllDestinations() []Destination {
+	destinations := []Destination{Destination{ChannelID: s.TargetChannelID, Format: FormatFull}}
+
+	for _, d := range s.Destinations {
+		if d.ChannelID == s.TargetChannelID {
+			continue
+		}
+		destinations = append(destinations, d)
+	}
+
+	return destinations
+}
+
+// TracksBlockers reports whether blockers are followed up from one standup
+// to the next, which takes the blocker question to be set.
+func (s *Setting) TracksBlockers() bool {
+	return s.BlockerQuestion > 0 && s.BlockerQuestion <= len(s.Questions)
+}
+
+// BlockerIndex returns the index of the question asking for blockers, or
+// -1 when there is none. Without a blocker question set, summaries still
+// highlight a question mentioning blockers.
+func (s *Setting) BlockerIndex() int {
+	if s.BlockerQuestion > 0 && s.BlockerQuestion <= len(s.Questions) {
+		return s.BlockerQuestion - 1
+	}
+
+	for i, q := range s.Questions {
+		if strings.Contains(strings.ToLower(q), "block") {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// ParseDestinations reads destinations written one per line as an ID and
+// an optional format, e.g. "C0123ABCD compact".
+func ParseDestinations(text string) ([]Destination, error) {
+	var destinations []Destination
+
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("%q should be an ID and a format", strings.TrimSpace(line))
+		}
+
+		d := Destination{ChannelID: fields[0], Format: FormatFull}
+		if len(fields) == 2 {
+			d.Format = strings.ToLower(fields[1])
+		}
+
+		switch d.Format {
+		case FormatFull, FormatBlockers, FormatCompact:
+		default:
+			return nil, fmt.Errorf("unknown format %q, use full, blockers or compact", d.Format)
+		}
+
+		destinations = append(destinations, d)
+	}
+
+	return destinations, nil
+}
+
+// FormatDestinations is the reverse of ParseDestinations.
+func FormatDestinations(destinations []Destination) string {
+	lines := make([]string, len(destinations))
+	for i, d := range destinations {
+		lines[i] = d.ChannelID + " " + d.Format
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ParseBlockerQuestion reads the number of the blocker question, optionally
+// followed by the channel or user ID to escalate blockers to, e.g.
+// "3 C0123ABCD".
+func ParseBlockerQuestion(text string, questions int) (int, string, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return 0, "", nil
+	}
+	if len(fields) > 2 {
+		return 0, "", fmt.Errorf("%q should be a question number and an ID", strings.TrimSpace(text))
+	}
+
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 1 || n > questions {
+		return 0, "", fmt.Errorf("%q isn't the number of a question", fields[0])
+	}
+
+	var escalation string
+	if len(fields) == 2 {
+		escalation = fields[1]
+	}
+
+	return n, escalation, nil
+}
+
+// FormatBlockerQuestion is the reverse of ParseBlockerQuestion.
+func FormatBlockerQuestion(question int, escalationChannelID string) string {
+	if question == 0 {
+		return ""
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("%d %s", question, escalationChannelID))
+}
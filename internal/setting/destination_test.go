@@ -0,0 +1,79 @@
+package setting
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDestinationsSuccess(t *testing.T) {
+	got, err := ParseDestinations("C1\n\n  C2 compact\nU1 Blockers\n")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	want := []Destination{
+		Destination{ChannelID: "C1", Format: FormatFull},
+		Destination{ChannelID: "C2", Format: FormatCompact},
+		Destination{ChannelID: "U1", Format: FormatBlockers},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Want %+v, got %+v", want, got)
+	}
+}
+
+func TestParseDestinationsFailure(t *testing.T) {
+	for _, text := range []string{"C1 short", "C1 full extra"} {
+		if _, err := ParseDestinations(text); err == nil {
+			t.Fatalf("Want an error for %q", text)
+		}
+	}
+}
+
+func TestAllDestinations(t *testing.T) {
+	s := &Setting{
+		TargetChannelID: "C1",
+		Destinations: []Destination{
+			Destination{ChannelID: "C1", Format: FormatCompact},
+			Destination{ChannelID: "U1", Format: FormatBlockers},
+		},
+	}
+
+	want := []Destination{
+		Destination{ChannelID: "C1", Format: FormatFull},
+		Destination{ChannelID: "U1", Format: FormatBlockers},
+	}
+	if got := s.AllDestinations(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Want %+v, got %+v", want, got)
+	}
+}
+
+func TestParseBlockerQuestion(t *testing.T) {
+	question, channelID, err := ParseBlockerQuestion(" 3 C0123ABCD ", 3)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+	if question != 3 || channelID != "C0123ABCD" {
+		t.Fatalf("Unexpected %d %s", question, channelID)
+	}
+
+	for _, text := range []string{"4", "0", "x", "1 C1 C2"} {
+		if _, _, err := ParseBlockerQuestion(text, 3); err == nil {
+			t.Fatalf("Want an error for %q", text)
+		}
+	}
+}
+
+func TestTracksBlockers(t *testing.T) {
+	s := &Setting{Questions: []string{"What did you do?", "Any blockers?"}}
+	if s.TracksBlockers() {
+		t.Fatal("Want no tracking without a blocker question")
+	}
+	if s.BlockerIndex() != 1 {
+		t.Fatalf("Want the blocker question to be guessed, got %d", s.BlockerIndex())
+	}
+
+	s.BlockerQuestion = 2
+	if !s.TracksBlockers() {
+		t.Fatal("Want blockers to be tracked")
+	}
+}
@@ -9,9 +9,22 @@ import (
 var settingsTable = os.Getenv("SETTINGS_TABLE")
 
 type Setting struct {
-	TargetChannelID string   `dynamo:"target_channel_id"`
-	Questions       []string `dynamo:"questions"`
-	UserIDs         []string `dynamo:"user_ids,set"`
+	TargetChannelID     string        `dynamo:"target_channel_id"`
+	Questions           []string      `dynamo:"questions"`
+	UserIDs             []string      `dynamo:"user_ids,set"`
+	TeamID              string        `dynamo:"team_id"`
+	ScheduleExpression  string        `dynamo:"schedule_expression"`
+	MembersFromChannel  bool          `dynamo:"members_from_channel"`
+	Paused              bool          `dynamo:"paused"`
+	Disabled            bool          `dynamo:"disabled"`
+	Threaded            bool          `dynamo:"threaded"`
+	SummaryTemplate     string        `dynamo:"summary_template"`
+	Destinations        []Destination `dynamo:"destinations"`
+	BlockerQuestion     int           `dynamo:"blocker_question"`
+	EscalationChannelID string        `dynamo:"escalation_channel_id"`
+	PlanQuestion        int           `dynamo:"plan_question"`
+	RecapQuestion       int           `dynamo:"recap_question"`
+	NotifyMentions      bool          `dynamo:"notify_mentions"`
 }
 
 func Get(db *dynamo.DB, targetChannelID string) (*Setting, error) {
@@ -25,13 +38,14 @@ func Get(db *dynamo.DB, targetChannelID string) (*Setting, error) {
 	return &s, nil
 }
 
-func Initial(db *dynamo.DB, targetChannelID string, questions []string, userIDs []string) error {
+func Initial(db *dynamo.DB, teamID string, targetChannelID string, questions []string, userIDs []string) error {
 	table := db.Table(settingsTable)
 
 	s := Setting{
 		TargetChannelID: targetChannelID,
 		Questions:       questions,
 		UserIDs:         userIDs,
+		TeamID:          teamID,
 	}
 	if err := table.Put(s).Run(); err != nil {
 		return err
@@ -40,3 +54,128 @@ func Initial(db *dynamo.DB, targetChannelID string, questions []string, userIDs
 	return nil
 
 }
+
+// All returns every setting, for schedulers which have to look at all of
+// them.
+func All(db *dynamo.DB) ([]Setting, error) {
+	table := db.Table(settingsTable)
+
+	var settings []Setting
+	if err := table.Scan().All(&settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func SetSchedule(db *dynamo.DB, targetChannelID string, teamID string, scheduleExpression string) error {
+	table := db.Table(settingsTable)
+
+	err := table.Update("target_channel_id", targetChannelID).
+		Set("team_id", teamID).
+		Set("schedule_expression", scheduleExpression).
+		Run()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Setting) Save(db *dynamo.DB) error {
+	table := db.Table(settingsTable)
+
+	if err := table.Put(s).Run(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Active reports whether stand-ups should be started for the setting.
+func (s *Setting) Active() bool {
+	return !s.Paused && !s.Disabled
+}
+
+func ByTeam(db *dynamo.DB, teamID string) ([]Setting, error) {
+	table := db.Table(settingsTable)
+
+	var settings []Setting
+	if err := table.Scan().Filter("'team_id' = ?", teamID).All(&settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// SetPaused pauses a setting while its channel is archived.
+func SetPaused(db *dynamo.DB, targetChannelID string, paused bool) error {
+	table := db.Table(settingsTable)
+
+	err := table.Update("target_channel_id", targetChannelID).
+		Set("paused", paused).
+		If("attribute_exists('target_channel_id')").
+		Run()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetDisabled disables a setting when the app is removed from its team.
+func SetDisabled(db *dynamo.DB, targetChannelID string, disabled bool) error {
+	table := db.Table(settingsTable)
+
+	err := table.Update("target_channel_id", targetChannelID).
+		Set("disabled", disabled).
+		If("attribute_exists('target_channel_id')").
+		Run()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetNotifyMentions sets whether users mentioned in answers are sent a link
+// to the summary.
+func SetNotifyMentions(db *dynamo.DB, targetChannelID string, notify bool) error {
+	table := db.Table(settingsTable)
+
+	err := table.Update("target_channel_id", targetChannelID).
+		Set("notify_mentions", notify).
+		If("attribute_exists('target_channel_id')").
+		Run()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func AddUser(db *dynamo.DB, targetChannelID string, userID string) error {
+	table := db.Table(settingsTable)
+
+	err := table.Update("target_channel_id", targetChannelID).
+		AddStringsToSet("user_ids", userID).
+		Run()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func RemoveUser(db *dynamo.DB, targetChannelID string, userID string) error {
+	table := db.Table(settingsTable)
+
+	err := table.Update("target_channel_id", targetChannelID).
+		DeleteStringsFromSet("user_ids", userID).
+		Run()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -78,7 +78,7 @@ func TestGetSuccess(t *testing.T) {
 	}
 
 	if !reflect.DeepEqual(got, want) {
-		t.Fatalf("Want %q, got %q", want, got)
+		t.Fatalf("Want %+v, got %+v", want, got)
 	}
 }
 
@@ -92,7 +92,7 @@ func TestInitialSuccess(t *testing.T) {
 	mockedClient := &mockedDynamo{Resp: want}
 	db := dynamo.NewFromIface(mockedClient)
 
-	err := Initial(db, want.TargetChannelID, want.Questions, want.UserIDs)
+	err := Initial(db, "team", want.TargetChannelID, want.Questions, want.UserIDs)
 	if err != nil {
 		t.Fatalf("%q", err)
 	}
@@ -0,0 +1,76 @@
+package slackapi
+
+// Block Kit blocks and elements used by the bot.
+// see https://api.slack.com/reference/block-kit/blocks
+
+type SectionBlock struct {
+	Type      string         `json:"type"`
+	BlockID   string         `json:"block_id,omitempty"`
+	Text      *TextObject    `json:"text,omitempty"`
+	Accessory *ButtonElement `json:"accessory,omitempty"`
+}
+
+type ContextBlock struct {
+	Type     string        `json:"type"`
+	Elements []interface{} `json:"elements"`
+}
+
+type ActionsBlock struct {
+	Type     string        `json:"type"`
+	Elements []interface{} `json:"elements"`
+}
+
+type DividerBlock struct {
+	Type string `json:"type"`
+}
+
+type TextObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type ImageElement struct {
+	Type     string `json:"type"`
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+type ButtonElement struct {
+	Type     string     `json:"type"`
+	Text     TextObject `json:"text"`
+	ActionID string     `json:"action_id"`
+	Value    string     `json:"value,omitempty"`
+}
+
+func Section(text string) SectionBlock {
+	return SectionBlock{Type: "section", Text: &TextObject{Type: "mrkdwn", Text: text}}
+}
+
+func Context(elements ...interface{}) ContextBlock {
+	return ContextBlock{Type: "context", Elements: elements}
+}
+
+func Actions(elements ...interface{}) ActionsBlock {
+	return ActionsBlock{Type: "actions", Elements: elements}
+}
+
+func Divider() DividerBlock {
+	return DividerBlock{Type: "divider"}
+}
+
+func Markdown(text string) TextObject {
+	return TextObject{Type: "mrkdwn", Text: text}
+}
+
+func Image(url string, altText string) ImageElement {
+	return ImageElement{Type: "image", ImageURL: url, AltText: altText}
+}
+
+func Button(text string, actionID string, value string) *ButtonElement {
+	return &ButtonElement{
+		Type:     "button",
+		Text:     TextObject{Type: "plain_text", Text: text},
+		ActionID: actionID,
+		Value:    value,
+	}
+}
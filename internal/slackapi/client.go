@@ -0,0 +1,302 @@
+package slackapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackhttp"
+)
+
+const defaultEndpoint = "https://slack.com/api/"
+
+// Error is returned when Slack answers a call with "ok": false.
+type Error struct {
+	Method string
+	Code   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("slack: %s failed: %s", e.Method, e.Code)
+}
+
+// unreachable are the codes of a channel the bot can't post to until
+// someone changes it, e.g. invites the bot or fixes a setting.
+var unreachable = map[string]bool{
+	"channel_not_found": true,
+	"not_in_channel":    true,
+	"is_archived":       true,
+	"user_not_found":    true,
+	"user_disabled":     true,
+	"cannot_dm_bot":     true,
+}
+
+// IsUnreachable reports whether an error is Slack refusing a channel or a
+// user for good, which retrying won't change.
+func IsUnreachable(err error) bool {
+	e, ok := err.(*Error)
+	return ok && unreachable[e.Code]
+}
+
+type response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+type client struct {
+	token      string
+	endpoint   string
+	httpClient *http.Client
+}
+
+// New returns the production Client authenticating with token.
+func New(token string) Client {
+	return &client{
+		token:      token,
+		endpoint:   defaultEndpoint,
+		httpClient: slackhttp.Client,
+	}
+}
+
+type postMessageRequest struct {
+	Message
+	Channel   string `json:"channel"`
+	Timestamp string `json:"ts,omitempty"`
+	AsUser    bool   `json:"as_user"`
+}
+
+func (c *client) PostMessage(ctx context.Context, channelID string, msg Message) (string, error) {
+	var resp struct {
+		Timestamp string `json:"ts"`
+	}
+
+	req := postMessageRequest{Message: msg, Channel: channelID, AsUser: true}
+	if err := c.call(ctx, "chat.postMessage", req, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Timestamp, nil
+}
+
+func (c *client) UpdateMessage(ctx context.Context, channelID string, timestamp string, msg Message) error {
+	// A message can't be moved to another thread
+	msg.ThreadTS = ""
+
+	req := postMessageRequest{Message: msg, Channel: channelID, Timestamp: timestamp, AsUser: true}
+	return c.call(ctx, "chat.update", req, nil)
+}
+
+func (c *client) GetUserInfo(ctx context.Context, userID string) (*User, error) {
+	var resp struct {
+		User User `json:"user"`
+	}
+
+	if err := c.call(ctx, "users.info", url.Values{"user": {userID}}, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp.User, nil
+}
+
+func (c *client) GetUserProfile(ctx context.Context, userID string) (*Profile, error) {
+	var resp struct {
+		Profile Profile `json:"profile"`
+	}
+
+	if err := c.call(ctx, "users.profile.get", url.Values{"user": {userID}}, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp.Profile, nil
+}
+
+func (c *client) AuthTest(ctx context.Context) (*Identity, error) {
+	var resp Identity
+
+	if err := c.call(ctx, "auth.test", url.Values{}, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func (c *client) OpenDialog(ctx context.Context, triggerID string, dialog Dialog) error {
+	req := struct {
+		TriggerID string `json:"trigger_id"`
+		Dialog    Dialog `json:"dialog"`
+	}{triggerID, dialog}
+
+	return c.call(ctx, "dialog.open", req, nil)
+}
+
+func (c *client) GetConversationMembers(ctx context.Context, channelID string) ([]string, error) {
+	var members []string
+	cursor := ""
+
+	for {
+		var resp struct {
+			Members          []string `json:"members"`
+			ResponseMetadata struct {
+				NextCursor string `json:"next_cursor"`
+			} `json:"response_metadata"`
+		}
+
+		params := url.Values{"channel": {channelID}, "limit": {"200"}}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+
+		if err := c.call(ctx, "conversations.members", params, &resp); err != nil {
+			return nil, err
+		}
+
+		members = append(members, resp.Members...)
+
+		cursor = resp.ResponseMetadata.NextCursor
+		if cursor == "" {
+			return members, nil
+		}
+	}
+}
+
+func (c *client) OpenDM(ctx context.Context, userID string) (string, error) {
+	var resp struct {
+		Channel Channel `json:"channel"`
+	}
+
+	if err := c.call(ctx, "conversations.open", url.Values{"users": {userID}}, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Channel.ID, nil
+}
+
+func (c *client) DeleteMessage(ctx context.Context, channelID string, timestamp string) error {
+	return c.call(ctx, "chat.delete", url.Values{"channel": {channelID}, "ts": {timestamp}}, nil)
+}
+
+func (c *client) GetPermalink(ctx context.Context, channelID string, timestamp string) (string, error) {
+	var resp struct {
+		Permalink string `json:"permalink"`
+	}
+
+	if err := c.call(ctx, "chat.getPermalink", url.Values{"channel": {channelID}, "message_ts": {timestamp}}, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Permalink, nil
+}
+
+// call posts params to a Web API method, as a form when given url.Values
+// and as JSON otherwise, and decodes a successful response into out.
+func (c *client) call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	var body []byte
+	var contentType string
+
+	if values, ok := params.(url.Values); ok {
+		body = []byte(values.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	} else {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		body = b
+		contentType = "application/json; charset=utf-8"
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(c.endpoint, "/")+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	b, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack: %s failed: %s", method, httpResp.Status)
+	}
+
+	var resp response
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return err
+	}
+	if !resp.OK {
+		return &Error{Method: method, Code: resp.Error}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(b, out)
+}
+
+// OpenConnection returns a Socket Mode websocket URL. It needs a client
+// created with an app-level token.
+// see https://api.slack.com/apis/connections/socket
+func OpenConnection(ctx context.Context, cl Client) (string, error) {
+	c, ok := cl.(*client)
+	if !ok {
+		return "", fmt.Errorf("slack: %T can't open a connection", cl)
+	}
+
+	var resp struct {
+		URL string `json:"url"`
+	}
+
+	if err := c.call(ctx, "apps.connections.open", url.Values{}, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.URL, nil
+}
+
+// Respond posts a message to the response_url of an interaction, visible
+// only to the user who triggered it.
+// see https://api.slack.com/interactivity/handling#message_responses
+func Respond(ctx context.Context, responseURL string, msg Message) error {
+	body, err := json.Marshal(struct {
+		Message
+		ResponseType    string `json:"response_type"`
+		ReplaceOriginal bool   `json:"replace_original"`
+	}{msg, "ephemeral", false})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, responseURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := slackhttp.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack: response failed: %s", resp.Status)
+	}
+
+	return nil
+}
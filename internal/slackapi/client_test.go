@@ -0,0 +1,153 @@
+package slackapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*client, func()) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			t.Errorf("Unexpected authorization header: %q", r.Header.Get("Authorization"))
+		}
+		handler(w, r)
+	}))
+
+	return &client{token: "xoxb-test", endpoint: ts.URL, httpClient: ts.Client()}, ts.Close
+}
+
+func TestPostMessageSuccess(t *testing.T) {
+	cl, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+
+		var req postMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("%q", err)
+		}
+		if req.Channel != "C1" || req.Text != "hello" || !req.AsUser {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		w.Write([]byte(`{"ok":true,"ts":"1500000000.000100"}`))
+	})
+	defer done()
+
+	ts, err := cl.PostMessage(context.Background(), "C1", Message{Text: "hello"})
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if ts != "1500000000.000100" {
+		t.Fatalf("Want %q, got %q", "1500000000.000100", ts)
+	}
+}
+
+func TestGetUserInfoSuccess(t *testing.T) {
+	cl, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("user") != "U1" {
+			t.Errorf("Unexpected user: %q", r.FormValue("user"))
+		}
+
+		w.Write([]byte(`{"ok":true,"user":{"id":"U1","tz":"Asia/Tokyo","profile":{"real_name":"Alice"}}}`))
+	})
+	defer done()
+
+	u, err := cl.GetUserInfo(context.Background(), "U1")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if u.TZ != "Asia/Tokyo" || u.Profile.RealName != "Alice" {
+		t.Fatalf("Unexpected user: %+v", u)
+	}
+}
+
+func TestCallReturnsSlackError(t *testing.T) {
+	cl, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+	})
+	defer done()
+
+	err := cl.UpdateMessage(context.Background(), "C1", "1.0", Message{Text: "hello"})
+
+	slackErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Want *Error, got %T", err)
+	}
+
+	if slackErr.Code != "channel_not_found" {
+		t.Fatalf("Want %q, got %q", "channel_not_found", slackErr.Code)
+	}
+}
+
+func TestExchangeCodeSuccess(t *testing.T) {
+	cl, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth.v2.access" || r.FormValue("code") != "code1" || r.FormValue("client_secret") != "secret" {
+			t.Errorf("Unexpected request: %s %v", r.URL.Path, r.Form)
+		}
+
+		w.Write([]byte(`{"ok":true,"access_token":"xoxb-1","bot_user_id":"UBOT","team":{"id":"T1","name":"Team"},"authed_user":{"id":"U1"}}`))
+	})
+	defer done()
+
+	resp, err := ExchangeCode(context.Background(), cl, "client", "secret", "code1", "https://example.com/oauth/redirect")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if resp.AccessToken != "xoxb-1" || resp.Team.ID != "T1" || resp.AuthedUser.ID != "U1" {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestGetConversationMembersPaginates(t *testing.T) {
+	cl, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("channel") != "C1" {
+			t.Errorf("Unexpected channel: %q", r.FormValue("channel"))
+		}
+
+		switch r.FormValue("cursor") {
+		case "":
+			w.Write([]byte(`{"ok":true,"members":["U1","U2"],"response_metadata":{"next_cursor":"next"}}`))
+		case "next":
+			w.Write([]byte(`{"ok":true,"members":["U3"],"response_metadata":{"next_cursor":""}}`))
+		default:
+			t.Errorf("Unexpected cursor: %q", r.FormValue("cursor"))
+		}
+	})
+	defer done()
+
+	members, err := cl.GetConversationMembers(context.Background(), "C1")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(members) != 3 || members[0] != "U1" || members[2] != "U3" {
+		t.Fatalf("Unexpected members: %v", members)
+	}
+}
+
+func TestUpdateMessageDropsThread(t *testing.T) {
+	cl, done := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req postMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("%q", err)
+		}
+		if req.Timestamp != "1.0" || req.ThreadTS != "" {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		w.Write([]byte(`{"ok":true}`))
+	})
+	defer done()
+
+	err := cl.UpdateMessage(context.Background(), "C1", "1.0", Message{Text: "hello", ThreadTS: "0.5"})
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+}
@@ -0,0 +1,68 @@
+package slackapi
+
+// Dialog is a legacy interactive dialog.
+// see https://api.slack.com/dialogs
+type Dialog struct {
+	CallbackID  string          `json:"callback_id"`
+	Title       string          `json:"title"`
+	SubmitLabel string          `json:"submit_label,omitempty"`
+	State       string          `json:"state,omitempty"`
+	Elements    []DialogElement `json:"elements"`
+}
+
+type DialogElement struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype,omitempty"`
+	Label       string `json:"label"`
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	Placeholder string `json:"placeholder,omitempty"`
+	Hint        string `json:"hint,omitempty"`
+	Optional    bool   `json:"optional,omitempty"`
+	MaxLength   int    `json:"max_length,omitempty"`
+	DataSource  string `json:"data_source,omitempty"`
+	// Options of a static select element.
+	Options []DialogOption `json:"options,omitempty"`
+}
+
+type DialogOption struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// InteractionCallback is the payload Slack posts to the interactive
+// endpoint, e.g. on a dialog submission.
+type InteractionCallback struct {
+	Type        string            `json:"type"`
+	CallbackID  string            `json:"callback_id"`
+	State       string            `json:"state"`
+	ResponseURL string            `json:"response_url"`
+	Team        Team              `json:"team"`
+	Channel     Channel           `json:"channel"`
+	User        UserRef           `json:"user"`
+	Submission  map[string]string `json:"submission"`
+	Actions     []Action          `json:"actions"`
+}
+
+// Action is a clicked block element of a block_actions interaction.
+type Action struct {
+	ActionID        string `json:"action_id"`
+	BlockID         string `json:"block_id"`
+	Value           string `json:"value"`
+	ActionTimestamp string `json:"action_ts"`
+}
+
+type Team struct {
+	ID     string `json:"id"`
+	Domain string `json:"domain"`
+}
+
+type Channel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,111 @@
+package slackapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Fake is an in-memory Client for tests. It serves users and profiles from
+// its maps and records every message posted or updated.
+type Fake struct {
+	Identity Identity
+	Users    map[string]User
+	Profiles map[string]Profile
+	Members  map[string][]string
+
+	mu      sync.Mutex
+	ts      int
+	Posted  []FakeMessage
+	Updated []FakeMessage
+	Deleted []FakeMessage
+	Dialogs []Dialog
+}
+
+// FakeMessage is a message recorded by Fake.
+type FakeMessage struct {
+	ChannelID string
+	Timestamp string
+	Message   Message
+}
+
+var _ Client = &Fake{}
+
+func (f *Fake) PostMessage(ctx context.Context, channelID string, msg Message) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.ts++
+	ts := fmt.Sprintf("1500000000.%06d", f.ts)
+	f.Posted = append(f.Posted, FakeMessage{ChannelID: channelID, Timestamp: ts, Message: msg})
+
+	return ts, nil
+}
+
+func (f *Fake) UpdateMessage(ctx context.Context, channelID string, timestamp string, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Updated = append(f.Updated, FakeMessage{ChannelID: channelID, Timestamp: timestamp, Message: msg})
+
+	return nil
+}
+
+func (f *Fake) DeleteMessage(ctx context.Context, channelID string, timestamp string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Deleted = append(f.Deleted, FakeMessage{ChannelID: channelID, Timestamp: timestamp})
+
+	return nil
+}
+
+func (f *Fake) GetUserInfo(ctx context.Context, userID string) (*User, error) {
+	u, ok := f.Users[userID]
+	if !ok {
+		return nil, &Error{Method: "users.info", Code: "user_not_found"}
+	}
+
+	return &u, nil
+}
+
+func (f *Fake) GetUserProfile(ctx context.Context, userID string) (*Profile, error) {
+	p, ok := f.Profiles[userID]
+	if !ok {
+		return nil, &Error{Method: "users.profile.get", Code: "user_not_found"}
+	}
+
+	return &p, nil
+}
+
+func (f *Fake) AuthTest(ctx context.Context) (*Identity, error) {
+	identity := f.Identity
+	return &identity, nil
+}
+
+func (f *Fake) OpenDialog(ctx context.Context, triggerID string, dialog Dialog) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Dialogs = append(f.Dialogs, dialog)
+
+	return nil
+}
+
+func (f *Fake) GetConversationMembers(ctx context.Context, channelID string) ([]string, error) {
+	members, ok := f.Members[channelID]
+	if !ok {
+		return nil, &Error{Method: "conversations.members", Code: "channel_not_found"}
+	}
+
+	return members, nil
+}
+
+func (f *Fake) OpenDM(ctx context.Context, userID string) (string, error) {
+	return "D" + userID, nil
+}
+
+func (f *Fake) GetPermalink(ctx context.Context, channelID string, timestamp string) (string, error) {
+	return "https://example.slack.com/archives/" + channelID + "/p" + strings.Replace(timestamp, ".", "", 1), nil
+}
@@ -0,0 +1,50 @@
+package slackapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// OAuthV2Response is the result of oauth.v2.access.
+type OAuthV2Response struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+	BotUserID   string `json:"bot_user_id"`
+	AppID       string `json:"app_id"`
+	Team        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"team"`
+	AuthedUser struct {
+		ID          string `json:"id"`
+		Scope       string `json:"scope"`
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+	} `json:"authed_user"`
+}
+
+// ExchangeCode trades the code of an OAuth redirect for tokens. It doesn't
+// need a token, so any client returned by New will do.
+// see https://api.slack.com/methods/oauth.v2.access
+func ExchangeCode(ctx context.Context, cl Client, clientID string, clientSecret string, code string, redirectURI string) (*OAuthV2Response, error) {
+	c, ok := cl.(*client)
+	if !ok {
+		return nil, fmt.Errorf("slack: %T can't exchange a code", cl)
+	}
+
+	params := url.Values{
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+	}
+
+	var resp OAuthV2Response
+	if err := c.call(ctx, "oauth.v2.access", params, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
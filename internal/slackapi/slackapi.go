@@ -0,0 +1,73 @@
+package slackapi
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Client is the subset of the Slack Web API used by the bot.
+type Client interface {
+	// PostMessage posts to a channel, or to a DM when given a user ID,
+	// and returns the timestamp of the posted message.
+	PostMessage(ctx context.Context, channelID string, msg Message) (string, error)
+	UpdateMessage(ctx context.Context, channelID string, timestamp string, msg Message) error
+	DeleteMessage(ctx context.Context, channelID string, timestamp string) error
+	GetUserInfo(ctx context.Context, userID string) (*User, error)
+	GetUserProfile(ctx context.Context, userID string) (*Profile, error)
+	AuthTest(ctx context.Context) (*Identity, error)
+	OpenDialog(ctx context.Context, triggerID string, dialog Dialog) error
+	// GetConversationMembers returns the IDs of every member of a channel.
+	GetConversationMembers(ctx context.Context, channelID string) ([]string, error)
+	// OpenDM returns the ID of the DM channel with a user, which messages
+	// posted to the user ID end up in.
+	OpenDM(ctx context.Context, userID string) (string, error)
+	// GetPermalink returns the URL of a message.
+	GetPermalink(ctx context.Context, channelID string, timestamp string) (string, error)
+}
+
+type Message struct {
+	Text        string       `json:"text,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	ThreadTS    string       `json:"thread_ts,omitempty"`
+	// Blocks is the JSON array of the Block Kit blocks of the message.
+	Blocks json.RawMessage `json:"blocks,omitempty"`
+}
+
+type Attachment struct {
+	AuthorName string            `json:"author_name,omitempty"`
+	AuthorIcon string            `json:"author_icon,omitempty"`
+	Fields     []AttachmentField `json:"fields,omitempty"`
+	Footer     string            `json:"footer,omitempty"`
+}
+
+type AttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type User struct {
+	ID      string  `json:"id"`
+	TeamID  string  `json:"team_id"`
+	Name    string  `json:"name"`
+	TZ      string  `json:"tz"`
+	IsBot   bool    `json:"is_bot"`
+	Deleted bool    `json:"deleted"`
+	Profile Profile `json:"profile"`
+}
+
+type Profile struct {
+	RealName    string `json:"real_name"`
+	DisplayName string `json:"display_name"`
+	Image32     string `json:"image_32"`
+	Image48     string `json:"image_48"`
+}
+
+// Identity is the result of auth.test, the user the token belongs to.
+type Identity struct {
+	TeamID string `json:"team_id"`
+	Team   string `json:"team"`
+	UserID string `json:"user_id"`
+	User   string `json:"user"`
+	BotID  string `json:"bot_id"`
+}
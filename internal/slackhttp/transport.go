@@ -0,0 +1,288 @@
+package slackhttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default is shared by every Slack client in the process so that the
+// per-method budgets apply across all of them.
+var Default = NewTransport(http.DefaultTransport)
+
+// Client is an *http.Client using Default.
+var Client = &http.Client{Transport: Default}
+
+func init() {
+	expvar.Publish("slack", expvar.Func(func() interface{} {
+		return Default.Stats()
+	}))
+}
+
+// Tiers of the Slack Web API rate limits, in requests per minute.
+// see https://api.slack.com/docs/rate-limits
+const (
+	Tier1 = 1
+	Tier2 = 20
+	Tier3 = 50
+	Tier4 = 100
+
+	// chat.postMessage allows roughly one message per second per channel
+	tierPostMessage = 60
+)
+
+var methodTiers = map[string]int{
+	"apps.connections.open": Tier1,
+	"auth.test":             Tier4,
+	"chat.postMessage":      tierPostMessage,
+	"chat.update":           Tier3,
+	"chat.delete":           Tier3,
+	"chat.getPermalink":     Tier4,
+	"conversations.info":    Tier3,
+	"conversations.members": Tier4,
+	"conversations.open":    Tier3,
+	"dialog.open":           Tier4,
+	"users.info":            Tier4,
+	"users.profile.get":     Tier4,
+	"views.open":            Tier4,
+}
+
+const defaultTier = Tier3
+
+// unsafeMethods have effects which a retry could repeat, like posting a
+// message twice. They are only retried when Slack hasn't handled the call.
+var unsafeMethods = map[string]bool{
+	"chat.postMessage":   true,
+	"chat.delete":        true,
+	"conversations.open": true,
+}
+
+// Stats is a snapshot of the counters of a Transport.
+type Stats struct {
+	Requests  int64 `json:"requests"`
+	Throttled int64 `json:"throttled"`
+	Retries   int64 `json:"retries"`
+	Failures  int64 `json:"failures"`
+	WaitedMs  int64 `json:"waited_ms"`
+}
+
+// Transport is an http.RoundTripper for the Slack Web API. It spaces out
+// calls according to the tier of each method, honors Retry-After on 429
+// responses and retries transient errors with jittered backoff.
+type Transport struct {
+	Base       http.RoundTripper
+	MaxRetries int
+	BaseDelay  time.Duration
+
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu       sync.Mutex
+	limiters map[string]*limiter
+
+	requests  int64
+	throttled int64
+	retries   int64
+	failures  int64
+	waited    int64
+}
+
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{
+		Base:       base,
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		sleep:      sleep,
+		limiters:   map[string]*limiter{},
+	}
+}
+
+func (t *Transport) Stats() Stats {
+	return Stats{
+		Requests:  atomic.LoadInt64(&t.requests),
+		Throttled: atomic.LoadInt64(&t.throttled),
+		Retries:   atomic.LoadInt64(&t.retries),
+		Failures:  atomic.LoadInt64(&t.failures),
+		WaitedMs:  atomic.LoadInt64(&t.waited) / int64(time.Millisecond),
+	}
+}
+
+// LogStats prints the counters of Default, meant to be deferred by handlers.
+func LogStats() {
+	s := Default.Stats()
+	log.Printf("slack api: requests=%d throttled=%d retries=%d failures=%d waited_ms=%d",
+		s.Requests, s.Throttled, s.Retries, s.Failures, s.WaitedMs)
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	method := apiMethod(req.URL.Path)
+
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if method != "" {
+			if err := t.wait(ctx, t.limiter(method).reserve()); err != nil {
+				return nil, err
+			}
+		}
+
+		r := req
+		if body != nil {
+			r = req.WithContext(ctx)
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		atomic.AddInt64(&t.requests, 1)
+		resp, err := t.Base.RoundTrip(r)
+
+		var delay time.Duration
+		switch {
+		case err != nil:
+			delay = t.backoff(attempt)
+		case resp.StatusCode == http.StatusTooManyRequests:
+			atomic.AddInt64(&t.throttled, 1)
+			delay = retryAfter(resp, t.backoff(attempt))
+			log.Printf("slack api: %s is rate limited, retry after %s", method, delay)
+		case resp.StatusCode >= 500:
+			delay = t.backoff(attempt)
+		default:
+			return resp, nil
+		}
+
+		// A 5xx or an error after the request was sent may come after Slack
+		// handled it
+		unsent := err == nil && resp.StatusCode == http.StatusTooManyRequests || isDialError(err)
+		if attempt >= t.MaxRetries || (method == "" || unsafeMethods[method]) && !unsent {
+			atomic.AddInt64(&t.failures, 1)
+			return resp, err
+		}
+
+		if resp != nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+
+		atomic.AddInt64(&t.retries, 1)
+		if err := t.wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// apiMethod returns the Web API method a request calls, or "" for other
+// Slack URLs like a response_url, which share no budget and may post.
+func apiMethod(p string) string {
+	dir, method := path.Split(p)
+	if dir != "/api/" {
+		return ""
+	}
+
+	return method
+}
+
+func (t *Transport) wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	atomic.AddInt64(&t.waited, int64(d))
+	return t.sleep(ctx, d)
+}
+
+// backoff doubles the delay on every attempt and adds up to 50% of jitter
+// so that concurrent callers don't retry in lockstep.
+func (t *Transport) backoff(attempt int) time.Duration {
+	d := t.BaseDelay << uint(attempt)
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+func (t *Transport) limiter(method string) *limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l, ok := t.limiters[method]
+	if !ok {
+		perMinute, ok := methodTiers[method]
+		if !ok {
+			perMinute = defaultTier
+		}
+
+		l = &limiter{interval: time.Minute / time.Duration(perMinute)}
+		t.limiters[method] = l
+	}
+
+	return l
+}
+
+// limiter hands out evenly spaced slots, allowing a burst of calls up to
+// one minute's budget before callers have to wait.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	burst := time.Minute - l.interval
+	if l.next.Before(now.Add(-burst)) {
+		l.next = now.Add(-burst)
+	}
+
+	l.next = l.next.Add(l.interval)
+	return l.next.Sub(now)
+}
+
+// isDialError reports whether the connection failed, so that the request
+// never reached Slack.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func retryAfter(resp *http.Response, fallback time.Duration) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return fallback
+	}
+
+	return time.Duration(secs) * time.Second
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+
+	return ioutil.ReadAll(req.Body)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
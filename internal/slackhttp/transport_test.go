@@ -0,0 +1,172 @@
+package slackhttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestTransport() (*Transport, *[]time.Duration) {
+	var slept []time.Duration
+
+	t := NewTransport(http.DefaultTransport)
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	return t, &slept
+}
+
+func TestRoundTripHonorsRetryAfter(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "channel=C1" {
+			t.Errorf("Want body to be resent, got %q", body)
+		}
+
+		if calls == 1 {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	tr, slept := newTestTransport()
+	cl := &http.Client{Transport: tr}
+
+	resp, err := cl.Post(ts.URL+"/api/chat.postMessage", "application/x-www-form-urlencoded", strings.NewReader("channel=C1"))
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Want 200, got %d", resp.StatusCode)
+	}
+
+	if len(*slept) != 1 || (*slept)[0] != 3*time.Second {
+		t.Fatalf("Want to wait 3s, got %v", *slept)
+	}
+
+	stats := tr.Stats()
+	if stats.Requests != 2 || stats.Throttled != 1 || stats.Retries != 1 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestRoundTripGivesUpAfterMaxRetries(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	tr, slept := newTestTransport()
+	cl := &http.Client{Transport: tr}
+
+	resp, err := cl.Get(ts.URL + "/api/users.info")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Want 503, got %d", resp.StatusCode)
+	}
+
+	if len(*slept) != tr.MaxRetries {
+		t.Fatalf("Want %d retries, got %v", tr.MaxRetries, *slept)
+	}
+
+	for i, d := range *slept {
+		min := tr.BaseDelay << uint(i)
+		if d < min || d > min+min/2 {
+			t.Fatalf("Backoff %d out of range: %s", i, d)
+		}
+	}
+
+	if tr.Stats().Failures != 1 {
+		t.Fatalf("Unexpected stats: %+v", tr.Stats())
+	}
+}
+
+func TestRoundTripDoesNotRepeatUnsafeMethods(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	tr, slept := newTestTransport()
+	cl := &http.Client{Transport: tr}
+
+	resp, err := cl.Post(ts.URL+"/api/chat.postMessage", "application/x-www-form-urlencoded", strings.NewReader("channel=C1"))
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+	resp.Body.Close()
+
+	if calls != 1 || len(*slept) != 0 {
+		t.Fatalf("Want no retry, got %d calls", calls)
+	}
+}
+
+func TestRoundTripRetriesUnsentUnsafeMethods(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	tr, slept := newTestTransport()
+	cl := &http.Client{Transport: tr}
+
+	if _, err := cl.Post(url+"/api/chat.postMessage", "application/x-www-form-urlencoded", strings.NewReader("channel=C1")); err == nil {
+		t.Fatal("Want an error from a closed server")
+	}
+
+	if len(*slept) != tr.MaxRetries {
+		t.Fatalf("Want %d retries, got %v", tr.MaxRetries, *slept)
+	}
+}
+
+func TestRoundTripKeepsNoLimiterOfResponseURLs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	tr, _ := newTestTransport()
+	cl := &http.Client{Transport: tr}
+
+	for _, p := range []string{"/actions/T1/1/abc", "/actions/T1/2/def", "/api/chat.postMessage"} {
+		resp, err := cl.Post(ts.URL+p, "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("%q", err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(tr.limiters) != 1 || tr.limiters["chat.postMessage"] == nil {
+		t.Fatalf("Want only the limiter of chat.postMessage, got %v", tr.limiters)
+	}
+}
+
+func TestLimiterSpacesCallsAfterBurst(t *testing.T) {
+	l := &limiter{interval: time.Minute / Tier2}
+
+	for i := 0; i < Tier2-1; i++ {
+		if d := l.reserve(); d > 0 {
+			t.Fatalf("Want call %d within the burst, got wait %s", i, d)
+		}
+	}
+
+	if d := l.reserve(); d <= 0 {
+		t.Fatalf("Want to wait once the budget is spent, got %s", d)
+	}
+}
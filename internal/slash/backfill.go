@@ -0,0 +1,174 @@
+package slash
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/thread"
+	"github.com/tsub/serverless-daily-standup-bot/internal/usercache"
+)
+
+// backfill starts the standup of a past day for the user running
+// `/standup backfill YYYY-MM-DD`.
+func backfill(ctx context.Context, query url.Values, args []string) (int, string, error) {
+	if len(args) != 1 {
+		return reply("Usage: /standup backfill YYYY-MM-DD")
+	}
+
+	date, err := time.Parse("2006-01-02", args[0])
+	if err != nil {
+		return reply(fmt.Sprintf("%q is not a date like 2018-09-03.", args[0]))
+	}
+
+	db := dynamo.New(session.New())
+
+	teamID := query.Get("team_id")
+	userID := query.Get("user_id")
+
+	s, err := settingOf(db, teamID, query.Get("channel_id"), userID)
+	if err != nil {
+		return 500, "", err
+	}
+	if s == nil {
+		return reply("You aren't a member of a stand-up here. Please run it in the target channel of your stand-up.")
+	}
+
+	botcl, cl, err := installation.Clients(db, teamID)
+	if err != nil {
+		return 500, "", err
+	}
+
+	u, err := usercache.Get(ctx, db, cl, userID)
+	if err != nil {
+		return 500, "", err
+	}
+
+	today, err := standup.Today(u.TZ)
+	if err != nil {
+		return 500, "", err
+	}
+	todayDate, _ := time.Parse("2006-01-02", today)
+
+	if !date.Before(todayDate) {
+		return reply("Only past days can be backfilled.")
+	}
+	if todayDate.Sub(date) > standup.BackfillDays*24*time.Hour {
+		return reply(fmt.Sprintf("Only the last %d days can be backfilled.", standup.BackfillDays))
+	}
+
+	standups, err := standup.Since(db, userID, args[0], true)
+	if err != nil {
+		return 500, "", err
+	}
+
+	// Answers go to the standup whose question was sent last, so a backfill
+	// can run alongside the standup of today
+	var st *standup.Standup
+	for i := range standups {
+		if standups[i].Late && standups[i].Open() {
+			return reply("Please finish or cancel your current backfill first.")
+		}
+		if standups[i].Date != args[0] {
+			continue
+		}
+		if standups[i].CurrentStatus() != standup.StatusExpired {
+			return reply(fmt.Sprintf("You already have a stand-up for %s.", args[0]))
+		}
+		st = &standups[i]
+	}
+
+	if st == nil {
+		questions := make([]standup.Question, len(s.Questions))
+		for i, text := range s.Questions {
+			questions[i] = standup.Question{Text: text}
+		}
+
+		st = standup.NewOn(args[0], userID, questions, s.TargetChannelID)
+		st.TeamID = teamID
+	}
+
+	if s.Threaded && st.ThreadTS == "" {
+		if err := joinThread(ctx, db, botcl, st); err != nil {
+			return 500, "", err
+		}
+	}
+
+	if err := st.Backfill(db); err != nil {
+		return 500, "", err
+	}
+
+	return reply(fmt.Sprintf("Starting your stand-up for %s, I'll send you the questions.", args[0]))
+}
+
+// joinThread puts a backfilled standup in the thread of its day, if the day
+// had one.
+func joinThread(ctx context.Context, db *dynamo.DB, cl slackapi.Client, st *standup.Standup) error {
+	existing, err := thread.Get(db, st.TargetChannelID, st.Date)
+	if err == dynamo.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Timestamp == "" {
+		// The parent message of the day was never posted
+		return nil
+	}
+
+	t, err := thread.Join(db, st.TargetChannelID, st.Date, st.UserID)
+	if err != nil {
+		return err
+	}
+	st.ThreadTS = t.Timestamp
+
+	return cl.UpdateMessage(ctx, st.TargetChannelID, t.Timestamp, slackapi.Message{Text: t.Text()})
+}
+
+// settingOf returns the setting a user takes part in, the one of the
+// channel when run from a target channel, or nil.
+func settingOf(db *dynamo.DB, teamID string, channelID string, userID string) (*setting.Setting, error) {
+	s, err := setting.Get(db, channelID)
+	if err != nil && err != dynamo.ErrNotFound {
+		return nil, err
+	}
+	if err == nil && s.Active() && contains(s.UserIDs, userID) {
+		return s, nil
+	}
+
+	settings, err := setting.ByTeam(db, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *setting.Setting
+	for i := range settings {
+		if !settings[i].Active() || !contains(settings[i].UserIDs, userID) {
+			continue
+		}
+		if found != nil {
+			// Ambiguous, the user has to pick a channel
+			return nil, nil
+		}
+		found = &settings[i]
+	}
+
+	return found, nil
+}
+
+func contains(vs []string, v string) bool {
+	for _, s := range vs {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,34 @@
+package slash
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestBackfillRejectsInvalidDate(t *testing.T) {
+	query := url.Values{"text": {"backfill 09/03"}, "team_id": {"T1"}, "user_id": {"U1"}}
+
+	status, body, err := handleQuery(context.Background(), query)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if status != 200 || !strings.Contains(body, `"response_type":"ephemeral"`) || !strings.Contains(body, "is not a date") {
+		t.Fatalf("Unexpected status %d, body %q", status, body)
+	}
+}
+
+func TestBackfillShowsUsage(t *testing.T) {
+	query := url.Values{"text": {"backfill"}}
+
+	_, body, err := handleQuery(context.Background(), query)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if !strings.Contains(body, "Usage") {
+		t.Fatalf("Want usage, got %q", body)
+	}
+}
@@ -0,0 +1,53 @@
+package slash
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/blocker"
+)
+
+// listBlockers shows the open blockers of the stand-up of the channel
+// `/standup blockers` is run in, the oldest first.
+func listBlockers(query url.Values) (int, string, error) {
+	db := dynamo.New(session.New())
+
+	blockers, err := blocker.Open(db, query.Get("channel_id"))
+	if err != nil {
+		return 500, "", err
+	}
+
+	return reply(blockersText(blockers))
+}
+
+func blockersText(blockers []blocker.Blocker) string {
+	if len(blockers) == 0 {
+		return "No open blockers in this channel."
+	}
+
+	sort.SliceStable(blockers, func(i, j int) bool {
+		return blockers[i].FirstSeen < blockers[j].FirstSeen
+	})
+
+	lines := []string{"Open blockers:"}
+	for _, b := range blockers {
+		lines = append(lines, fmt.Sprintf("• <@%s> %s _(%s)_", b.UserID, b.Text, age(b.Age())))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func age(days int) string {
+	switch days {
+	case 0:
+		return "since today"
+	case 1:
+		return "1 day"
+	default:
+		return fmt.Sprintf("%d days", days)
+	}
+}
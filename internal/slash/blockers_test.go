@@ -0,0 +1,23 @@
+package slash
+
+import (
+	"testing"
+
+	"github.com/tsub/serverless-daily-standup-bot/internal/blocker"
+)
+
+func TestBlockersText(t *testing.T) {
+	if got := blockersText(nil); got != "No open blockers in this channel." {
+		t.Fatalf("Unexpected %q", got)
+	}
+
+	blockers := []blocker.Blocker{
+		blocker.Blocker{UserID: "U2", Text: "CI is down", FirstSeen: "9999-01-02"},
+		blocker.Blocker{UserID: "U1", Text: "Waiting for review", FirstSeen: "9999-01-01"},
+	}
+
+	want := "Open blockers:\n• <@U1> Waiting for review _(since today)_\n• <@U2> CI is down _(since today)_"
+	if got := blockersText(blockers); got != want {
+		t.Fatalf("Want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,101 @@
+package slash
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/usercache"
+)
+
+// history shows the user running `/standup history [YYYY-MM-DD]` the
+// previous versions of their edited answers, of today by default.
+func history(ctx context.Context, query url.Values, args []string) (int, string, error) {
+	if len(args) > 1 {
+		return reply("Usage: /standup history [YYYY-MM-DD]")
+	}
+	if len(args) == 1 {
+		if _, err := time.Parse("2006-01-02", args[0]); err != nil {
+			return reply(fmt.Sprintf("%q is not a date like 2018-09-03.", args[0]))
+		}
+	}
+
+	db := dynamo.New(session.New())
+
+	userID := query.Get("user_id")
+
+	var date string
+	if len(args) == 1 {
+		date = args[0]
+	} else {
+		_, cl, err := installation.Clients(db, query.Get("team_id"))
+		if err != nil {
+			return 500, "", err
+		}
+
+		u, err := usercache.Get(ctx, db, cl, userID)
+		if err != nil {
+			return 500, "", err
+		}
+
+		if date, err = standup.Today(u.TZ); err != nil {
+			return 500, "", err
+		}
+	}
+
+	s, err := standup.Get(db, userID, date, false)
+	if err == dynamo.ErrNotFound {
+		return reply(fmt.Sprintf("You have no stand-up on %s.", date))
+	}
+	if err != nil {
+		return 500, "", err
+	}
+
+	return reply(historyText(s))
+}
+
+func historyText(s *standup.Standup) string {
+	var sections []string
+
+	for i, answer := range s.Answers {
+		if len(answer.Revisions) == 0 || i >= len(s.Questions) {
+			continue
+		}
+
+		lines := []string{fmt.Sprintf("*%s*", s.Questions[i].Text)}
+		for _, r := range answer.Revisions {
+			at := r.EditedAt
+			if at == "" {
+				at = answer.PostedAt
+			}
+			lines = append(lines, fmt.Sprintf("%s\n>%s", slackDate(at), strings.Replace(r.Text, "\n", "\n>", -1)))
+		}
+		lines = append(lines, fmt.Sprintf("%s _(current)_\n>%s", slackDate(answer.EditedAt), strings.Replace(answer.FullText(), "\n", "\n>", -1)))
+
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	if len(sections) == 0 {
+		return fmt.Sprintf("None of your answers on %s were edited.", s.Date)
+	}
+
+	return strings.Join(sections, "\n\n")
+}
+
+// slackDate formats a message timestamp in the timezone of the reader.
+func slackDate(ts string) string {
+	f, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return ts
+	}
+	t := time.Unix(int64(f), 0).UTC()
+
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s>", t.Unix(), t.Format(time.RFC3339))
+}
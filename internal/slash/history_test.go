@@ -0,0 +1,51 @@
+package slash
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+)
+
+func TestHistoryText(t *testing.T) {
+	s := &standup.Standup{
+		Date:      "2018-09-03",
+		Questions: []standup.Question{standup.Question{Text: "Yesterday?"}, standup.Question{Text: "Today?"}},
+		Answers: []standup.Answer{
+			standup.Answer{Text: "Release", PostedAt: "1535965200.000100"},
+			standup.Answer{
+				Text:      "Docs",
+				PostedAt:  "1535965260.000100",
+				EditedAt:  "1535965320.000000",
+				Revisions: []standup.Revision{standup.Revision{Text: "Dcos"}},
+			},
+		},
+	}
+
+	want := "*Today?*\n" +
+		"<!date^1535965260^{date_short_pretty} at {time}|2018-09-03T09:01:00Z>\n>Dcos\n" +
+		"<!date^1535965320^{date_short_pretty} at {time}|2018-09-03T09:02:00Z> _(current)_\n>Docs"
+	if got := historyText(s); got != want {
+		t.Fatalf("Want %q, got %q", want, got)
+	}
+
+	s.Answers[1].Revisions = nil
+	if got := historyText(s); !strings.Contains(got, "None of your answers") {
+		t.Fatalf("Unexpected %q", got)
+	}
+}
+
+func TestHistoryRejectsInvalidDate(t *testing.T) {
+	query := url.Values{"text": {"history 09/03"}, "user_id": {"U1"}}
+
+	_, body, err := handleQuery(context.Background(), query)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if !strings.Contains(body, "is not a date") {
+		t.Fatalf("Unexpected body %q", body)
+	}
+}
@@ -0,0 +1,37 @@
+package slash
+
+import (
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+)
+
+// mentions turns on or off `/standup mentions on|off` the links to the
+// summary sent to users mentioned in answers, for the stand-up of the
+// channel.
+func mentions(query url.Values, args []string) (int, string, error) {
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		return reply("Usage: /standup mentions on|off")
+	}
+
+	db := dynamo.New(session.New())
+
+	channelID := query.Get("channel_id")
+	if _, err := setting.Get(db, channelID); err == dynamo.ErrNotFound {
+		return reply("There is no stand-up in this channel. Please run it in the target channel of the stand-up.")
+	} else if err != nil {
+		return 500, "", err
+	}
+
+	notify := args[0] == "on"
+	if err := setting.SetNotifyMentions(db, channelID, notify); err != nil {
+		return 500, "", err
+	}
+
+	if notify {
+		return reply("Users mentioned in answers will be sent a link to the summary.")
+	}
+	return reply("Users mentioned in answers won't be sent a link to the summary anymore.")
+}
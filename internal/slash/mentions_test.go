@@ -0,0 +1,21 @@
+package slash
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMentionsShowsUsage(t *testing.T) {
+	query := url.Values{"text": {"mentions maybe"}}
+
+	_, body, err := handleQuery(context.Background(), query)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if !strings.Contains(body, "Usage: /standup mentions on|off") {
+		t.Fatalf("Want usage, got %q", body)
+	}
+}
@@ -0,0 +1,226 @@
+package slash
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/schedule"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+)
+
+func startSetting(ctx context.Context, query url.Values) (int, error) {
+	db := dynamo.New(session.New())
+
+	var userIDs string
+	var questions string
+	membersSource := "listed"
+	summaryStyle := "messages"
+	var summaryTemplate string
+	var destinations string
+	var blockers string
+	var carryOver string
+	// Don't handle error to skip "dynamo: no item found" error
+	s, _ := setting.Get(db, query.Get("channel_id"))
+	if s != nil {
+		userIDs = strings.Join(s.UserIDs, "\n")
+		questions = strings.Join(s.Questions, "\n")
+		summaryTemplate = s.SummaryTemplate
+		destinations = setting.FormatDestinations(s.Destinations)
+		blockers = setting.FormatBlockerQuestion(s.BlockerQuestion, s.EscalationChannelID)
+		carryOver = setting.FormatCarryOver(s.PlanQuestion, s.RecapQuestion)
+		if s.Threaded {
+			summaryStyle = "thread"
+		}
+		if s.MembersFromChannel {
+			membersSource = "channel"
+			// Kept in sync with the channel, nothing to type
+			userIDs = ""
+		}
+	}
+
+	scheduleExpression, err := schedule.Current().Get(ctx, query.Get("team_id"), query.Get("channel_id"))
+	if err != nil {
+		// Open the dialog anyway, the schedule can be typed again
+		log.Printf("failed to get schedule: %s", err)
+	}
+
+	cl, _, err := installation.Clients(db, query.Get("team_id"))
+	if err != nil {
+		return 500, err
+	}
+
+	dialog := slackapi.Dialog{
+		CallbackID: "setting",
+		Title:      "Setting",
+		Elements: []slackapi.DialogElement{
+			slackapi.DialogElement{
+				Type:  "select",
+				Label: "Members from",
+				Name:  "members_source",
+				Value: membersSource,
+				Options: []slackapi.DialogOption{
+					slackapi.DialogOption{Label: "The list below", Value: "listed"},
+					slackapi.DialogOption{Label: "Members of the target channel", Value: "channel"},
+				},
+			},
+			slackapi.DialogElement{
+				Type:     "textarea",
+				Label:    "Members",
+				Name:     "user_ids",
+				Value:    userIDs,
+				Hint:     "Please type user ID (not username)",
+				Optional: true,
+				Placeholder: `
+W012A3CDE
+W034B4FGH`,
+			},
+			slackapi.DialogElement{
+				Type:  "textarea",
+				Label: "Questions",
+				Name:  "questions",
+				Value: questions,
+				Hint:  "Please write multiple questions in multiple lines",
+				Placeholder: `
+What did you do yesterday?
+What will you do today?
+Anything blocking your progress?`,
+			},
+			slackapi.DialogElement{
+				Type:        "select",
+				Label:       "Target channel",
+				Name:        "target_channel_id",
+				Value:       query.Get("channel_id"),
+				DataSource:  "channels",
+				Placeholder: "Choose a channel",
+			},
+			slackapi.DialogElement{
+				Type:  "select",
+				Label: "Summaries",
+				Name:  "summary_style",
+				Value: summaryStyle,
+				Options: []slackapi.DialogOption{
+					slackapi.DialogOption{Label: "One message per member", Value: "messages"},
+					slackapi.DialogOption{Label: "One thread per day", Value: "thread"},
+				},
+			},
+			slackapi.DialogElement{
+				Type:     "textarea",
+				Label:    "Other destinations",
+				Name:     "destinations",
+				Value:    destinations,
+				Hint:     "One channel ID or user ID per line, followed by full, blockers or compact",
+				Optional: true,
+				Placeholder: `
+C0123ABCD compact
+U0123ABCD blockers`,
+			},
+			slackapi.DialogElement{
+				Type:        "text",
+				Label:       "Blocker question",
+				Name:        "blockers",
+				Value:       blockers,
+				Hint:        "Number of the question asking for blockers, optionally followed by a channel ID or user ID to escalate them to",
+				Placeholder: "3 C0123ABCD",
+				Optional:    true,
+			},
+			slackapi.DialogElement{
+				Type:        "text",
+				Label:       "Plan question",
+				Name:        "carry_over",
+				Value:       carryOver,
+				Hint:        "Number of the question asking for the plan of the day, followed by the number of the question showing it the next day",
+				Placeholder: "2 1",
+				Optional:    true,
+			},
+			slackapi.DialogElement{
+				Type:      "textarea",
+				Label:     "Summary template",
+				Name:      "summary_template",
+				Value:     summaryTemplate,
+				Hint:      "Go text/template with .User, .Date, .Day, .Status, .Items and .Answered. Start with [ to render Block Kit blocks. Leave empty for the default.",
+				Optional:  true,
+				MaxLength: 3000,
+				Placeholder: `
+*{{.User.Name}}* on {{.Day}}
+{{range .Answered}}• {{.Question}}: {{.Answer}}
+{{end}}`,
+			},
+			slackapi.DialogElement{
+				Type:        "text",
+				Label:       "Execution schedule",
+				Name:        "schedule_expression",
+				Value:       scheduleExpression,
+				Hint:        "https://docs.aws.amazon.com/AmazonCloudWatch/latest/events/ScheduledEvents.html",
+				Placeholder: "cron(0 1 ? * MON-FRI *)",
+			},
+		},
+	}
+	triggerID := query.Get("trigger_id")
+
+	err = cl.OpenDialog(ctx, triggerID, dialog)
+	if err != nil {
+		return 500, err
+	}
+
+	return 200, nil
+}
+
+func handleQuery(ctx context.Context, query url.Values) (status int, body string, err error) {
+	// for debug
+	log.Printf("query: %v", query)
+
+	args := strings.Fields(query.Get("text"))
+	if len(args) == 0 {
+		// TODO: Show help
+		return 200, "", nil
+	}
+
+	switch args[0] {
+	case "setting":
+		status, err = startSetting(ctx, query)
+		if err != nil {
+			return status, "", err
+		}
+	case "backfill":
+		return backfill(ctx, query, args[1:])
+	case "blockers":
+		return listBlockers(query)
+	case "history":
+		return history(ctx, query, args[1:])
+	case "mentions":
+		return mentions(query, args[1:])
+	default:
+		// TODO: Show help
+		status = 200
+	}
+
+	return status, "", nil
+}
+
+// reply answers the command with a message only its user can see.
+func reply(text string) (int, string, error) {
+	b, err := json.Marshal(map[string]string{"response_type": "ephemeral", "text": text})
+	if err != nil {
+		return 500, "", err
+	}
+
+	return 200, string(b), nil
+}
+
+// Handle runs a slash command posted as a form and returns the status code
+// and JSON body of the response.
+func Handle(ctx context.Context, body []byte) (int, string, error) {
+	query, err := url.ParseQuery(string(body))
+	if err != nil {
+		return 400, "", nil
+	}
+
+	return handleQuery(ctx, query)
+}
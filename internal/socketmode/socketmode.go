@@ -0,0 +1,153 @@
+package socketmode
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+)
+
+// Envelope types sent by Slack over the websocket.
+const (
+	TypeHello         = "hello"
+	TypeDisconnect    = "disconnect"
+	TypeEventsAPI     = "events_api"
+	TypeSlashCommands = "slash_commands"
+	TypeInteractive   = "interactive"
+)
+
+type envelope struct {
+	EnvelopeID             string          `json:"envelope_id"`
+	Type                   string          `json:"type"`
+	Payload                json.RawMessage `json:"payload"`
+	AcceptsResponsePayload bool            `json:"accepts_response_payload"`
+	RetryAttempt           int             `json:"retry_attempt"`
+	Reason                 string          `json:"reason"`
+}
+
+type ack struct {
+	EnvelopeID string          `json:"envelope_id"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+}
+
+// Handler processes the payload of an envelope and may return a payload to
+// send back with the acknowledgement, e.g. dialog validation errors.
+type Handler func(ctx context.Context, envelopeType string, payload json.RawMessage) (json.RawMessage, error)
+
+// Client keeps a Socket Mode connection open, reconnecting whenever Slack
+// asks to or the connection drops.
+type Client struct {
+	// OpenURL returns the URL to connect to, apps.connections.open by default
+	OpenURL func(ctx context.Context) (string, error)
+	Handler Handler
+
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func New(appToken string, handler Handler) *Client {
+	cl := slackapi.New(appToken)
+
+	return &Client{
+		OpenURL: func(ctx context.Context) (string, error) {
+			return slackapi.OpenConnection(ctx, cl)
+		},
+		Handler:    handler,
+		MinBackoff: time.Second,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+// Run connects and handles envelopes until ctx is canceled.
+func (c *Client) Run(ctx context.Context) error {
+	backoff := c.MinBackoff
+
+	for {
+		connected, err := c.connect(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if connected {
+			backoff = c.MinBackoff
+		}
+		if err != nil {
+			log.Printf("socket mode: %s, reconnecting in %s", err, backoff)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+
+			if backoff *= 2; backoff > c.MaxBackoff {
+				backoff = c.MaxBackoff
+			}
+		}
+	}
+}
+
+// connect runs one connection, and reports whether Slack said hello so
+// that the backoff only grows on repeated failures.
+func (c *Client) connect(ctx context.Context) (bool, error) {
+	url, err := c.OpenURL(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	// Unblock ReadJSON when ctx is canceled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	var hello bool
+	for {
+		var e envelope
+		if err := conn.ReadJSON(&e); err != nil {
+			return hello, err
+		}
+
+		switch e.Type {
+		case TypeHello:
+			log.Println("socket mode: connected")
+			hello = true
+		case TypeDisconnect:
+			// Slack is about to close the connection, so open a new one
+			log.Printf("socket mode: disconnect requested: %s", e.Reason)
+			return hello, nil
+		default:
+			if e.EnvelopeID == "" {
+				continue
+			}
+
+			resp, err := c.Handler(ctx, e.Type, e.Payload)
+			if err != nil {
+				log.Printf("socket mode: %s envelope %s: %s", e.Type, e.EnvelopeID, err)
+			}
+
+			a := ack{EnvelopeID: e.EnvelopeID}
+			if e.AcceptsResponsePayload || e.Type == TypeInteractive {
+				a.Payload = resp
+			}
+
+			if err := conn.WriteJSON(a); err != nil {
+				return hello, err
+			}
+		}
+	}
+}
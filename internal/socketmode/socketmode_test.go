@@ -0,0 +1,133 @@
+package socketmode
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeSlack is a websocket server speaking the Socket Mode protocol. Each
+// connection gets one script of envelopes and records the acks it reads.
+type fakeSlack struct {
+	mu      sync.Mutex
+	scripts [][]envelope
+	acks    chan ack
+	conns   int
+}
+
+func (f *fakeSlack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	f.mu.Lock()
+	script := f.scripts[f.conns%len(f.scripts)]
+	f.conns++
+	f.mu.Unlock()
+
+	for _, e := range script {
+		if err := conn.WriteJSON(e); err != nil {
+			return
+		}
+
+		if e.EnvelopeID == "" {
+			continue
+		}
+
+		var a ack
+		if err := conn.ReadJSON(&a); err != nil {
+			return
+		}
+		f.acks <- a
+	}
+
+	// Keep the connection open until the client goes away
+	conn.ReadMessage()
+}
+
+func TestRunAcksAndReconnects(t *testing.T) {
+	fake := &fakeSlack{
+		acks: make(chan ack, 10),
+		scripts: [][]envelope{
+			{
+				{Type: TypeHello},
+				{EnvelopeID: "e1", Type: TypeEventsAPI, Payload: json.RawMessage(`{"type":"event_callback"}`)},
+				{Type: TypeDisconnect, Reason: "refresh_requested"},
+			},
+			{
+				{Type: TypeHello},
+				{EnvelopeID: "e2", Type: TypeInteractive, Payload: json.RawMessage(`{"callback_id":"setting"}`)},
+			},
+		},
+	}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var handled []string
+
+	cl := &Client{
+		OpenURL: func(ctx context.Context) (string, error) {
+			return "ws" + strings.TrimPrefix(ts.URL, "http"), nil
+		},
+		Handler: func(ctx context.Context, envelopeType string, payload json.RawMessage) (json.RawMessage, error) {
+			mu.Lock()
+			handled = append(handled, envelopeType)
+			mu.Unlock()
+
+			if envelopeType == TypeInteractive {
+				return json.RawMessage(`{"errors":[]}`), nil
+			}
+			return nil, nil
+		},
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- cl.Run(ctx) }()
+
+	for _, want := range []ack{
+		{EnvelopeID: "e1"},
+		{EnvelopeID: "e2", Payload: json.RawMessage(`{"errors":[]}`)},
+	} {
+		select {
+		case got := <-fake.acks:
+			if got.EnvelopeID != want.EnvelopeID || string(got.Payload) != string(want.Payload) {
+				t.Fatalf("Want ack %+v, got %+v", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for ack of %s", want.EnvelopeID)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after cancel")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 2 || handled[0] != TypeEventsAPI || handled[1] != TypeInteractive {
+		t.Fatalf("Unexpected handled envelopes: %q", handled)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.conns < 2 {
+		t.Fatalf("Want a reconnection, got %d connections", fake.conns)
+	}
+}
@@ -3,6 +3,8 @@ package standup
 import (
 	"errors"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/guregu/dynamo"
@@ -11,70 +13,487 @@ import (
 var standupsTable = os.Getenv("STANDUPS_TABLE")
 
 type Standup struct {
-	UserID          string     `dynamo:"user_id"`
-	Date            string     `dynamo:"date"`
-	Questions       []Question `dynamo:"questions"`
-	Answers         []Answer   `dynamo:"answers"`
-	TargetChannelID string     `dynamo:"target_channel_id"`
-	FinishedAt      string     `dynamo:"finished_at"`
+	UserID          string       `dynamo:"user_id"`
+	Date            string       `dynamo:"date"`
+	Questions       []Question   `dynamo:"questions"`
+	Answers         []Answer     `dynamo:"answers"`
+	TargetChannelID string       `dynamo:"target_channel_id"`
+	FinishedAt      string       `dynamo:"finished_at"`
+	TeamID          string       `dynamo:"team_id"`
+	Status          Status       `dynamo:"status"`
+	Transitions     []Transition `dynamo:"transitions"`
+	Late            bool         `dynamo:"late"`
+	ThreadTS        string       `dynamo:"thread_ts"`
+	Summaries       []Summary    `dynamo:"summaries"`
+	Escalation      *Escalation  `dynamo:"escalation"`
+	// Introduced is set once the intro was sent before the first question.
+	Introduced bool `dynamo:"introduced"`
+	// Version counts the writes of the standup, so that a write based on a
+	// stale read fails rather than losing the other.
+	Version int `dynamo:"version"`
 }
 
+// Escalation is the blocker of a standup posted to the escalation channel
+// of its setting.
+type Escalation struct {
+	ChannelID  string `dynamo:"channel_id"`
+	Timestamp  string `dynamo:"ts"`
+	ResolvedBy string `dynamo:"resolved_by"`
+	ResolvedAt string `dynamo:"resolved_at"`
+}
+
+// Summary is a summary message posted to a destination.
+type Summary struct {
+	ChannelID string `dynamo:"channel_id"`
+	Timestamp string `dynamo:"ts"`
+}
+
+// BackfillDays is how many days back a missed standup can be backfilled.
+const BackfillDays = 7
+
 type Answer struct {
+	// Text is the normalized text of the message, Raw the text as sent.
+	Text     string `dynamo:"text"`
+	Raw      string `dynamo:"raw"`
+	PostedAt string `dynamo:"posted_at"`
+	// EditedAt is the edited.ts of the message once it was edited.
+	EditedAt  string     `dynamo:"edited_at"`
+	Revisions []Revision `dynamo:"revisions"`
+	Files     []File     `dynamo:"files"`
+	// Replies are the messages added later in the thread of the question.
+	Replies []Reply `dynamo:"replies"`
+}
+
+// Reply is a message adding to an answer, kept apart so that it can be
+// edited or deleted on its own.
+type Reply struct {
 	Text     string `dynamo:"text"`
+	Raw      string `dynamo:"raw"`
 	PostedAt string `dynamo:"posted_at"`
+	Files    []File `dynamo:"files"`
+}
+
+// FullText returns the text of the answer followed by its replies.
+func (a Answer) FullText() string {
+	texts := []string{a.Text}
+	for _, r := range a.Replies {
+		texts = append(texts, r.Text)
+	}
+
+	return strings.TrimSpace(strings.Join(texts, "\n"))
+}
+
+// AllFiles returns the files of the answer and of its replies.
+func (a Answer) AllFiles() []File {
+	files := a.Files
+	for _, r := range a.Replies {
+		files = append(files, r.Files...)
+	}
+
+	return files
+}
+
+// File is a file shared with an answer.
+type File struct {
+	ID        string `dynamo:"id"`
+	Name      string `dynamo:"name"`
+	Permalink string `dynamo:"permalink"`
+}
+
+// Revision is a previous text of an edited answer. EditedAt is when it was
+// written, "" for the text first posted.
+type Revision struct {
+	Text     string `dynamo:"text"`
+	EditedAt string `dynamo:"edited_at"`
 }
 
 type Question struct {
 	Text     string `dynamo:"text"`
 	PostedAt string `dynamo:"posted_at"`
+	// FollowUp is asked in place of Text in the DM, e.g. whether an open
+	// blocker is still there.
+	FollowUp string `dynamo:"follow_up"`
 }
 
-func (s *Standup) AppendAnswer(db *dynamo.DB, answer Answer) error {
+var (
+	mu          sync.RWMutex
+	subscribers []func(Standup)
+)
+
+// Subscribe registers f to be called with every standup written by this
+// process. It stands in for DynamoDB Streams when running as a server.
+func Subscribe(f func(Standup)) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	subscribers = append(subscribers, f)
+}
+
+func notify(s Standup) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, f := range subscribers {
+		f(s)
+	}
+}
+
+// save writes the standup unless it was written since it was read, e.g. by
+// another job, which fails the job to be retried on the current standup.
+func (s *Standup) save(db *dynamo.DB) error {
 	table := db.Table(standupsTable)
 
-	s.Answers = append(s.Answers, answer)
-	if err := table.Put(s).Run(); err != nil {
+	version := s.Version
+	s.Version++
+
+	err := table.Put(s).If("attribute_not_exists($) OR $ = ?", "version", "version", version).Run()
+	if err != nil {
+		s.Version = version
+		return err
+	}
+	notify(*s)
+
+	return nil
+}
+
+// AppendAnswer records the answer to the current question, which completes
+// the standup on the last one. An answer already recorded, e.g. from an event
+// delivered twice, is ignored.
+func (s *Standup) AppendAnswer(db *dynamo.DB, answer Answer) error {
+	if answer.PostedAt != "" && s.HasAnswer(answer.PostedAt) {
+		return nil
+	}
+
+	if s.CurrentStatus() == StatusPending {
+		// Answered before the first question was recorded as sent
+		if err := s.transition(StatusAsking); err != nil {
+			return err
+		}
+	}
+	if s.CurrentStatus() != StatusAsking {
+		return &TransitionError{From: s.CurrentStatus(), To: StatusAsking}
+	}
+
+	if i := s.NextQuestion(); i < len(s.Answers) {
+		// The question of a deleted answer, asked again
+		s.Answers[i] = answer
+	} else {
+		s.Answers = append(s.Answers, answer)
+	}
+	if s.NextQuestion() >= len(s.Questions) {
+		if err := s.transition(StatusCompleted); err != nil {
+			return err
+		}
+	}
+
+	if err := s.save(db); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// UpdateAnswer replaces the text of an edited answer or reply, keeping the
+// previous text of the answer as a revision.
 func (s *Standup) UpdateAnswer(db *dynamo.DB, updateAnswer Answer) error {
-	table := db.Table(standupsTable)
+	for i := range s.Answers {
+		answer := &s.Answers[i]
+		revision := Revision{Text: answer.FullText(), EditedAt: answer.EditedAt}
 
-	for i, answer := range s.Answers {
 		if answer.PostedAt == updateAnswer.PostedAt {
-			s.Answers[i] = updateAnswer
+			if answer.Text == updateAnswer.Text {
+				// Changed otherwise, e.g. a link was unfurled
+				return nil
+			}
+
+			updateAnswer.Revisions = append(answer.Revisions, revision)
+			if len(updateAnswer.Files) == 0 {
+				updateAnswer.Files = answer.Files
+			}
+			updateAnswer.Replies = answer.Replies
+			*answer = updateAnswer
+
+			return s.save(db)
+		}
+
+		for j := range answer.Replies {
+			reply := &answer.Replies[j]
+			if reply.PostedAt != updateAnswer.PostedAt {
+				continue
+			}
+			if reply.Text == updateAnswer.Text {
+				return nil
+			}
+
+			answer.Revisions = append(answer.Revisions, revision)
+			answer.EditedAt = updateAnswer.EditedAt
+			reply.Text = updateAnswer.Text
+			reply.Raw = updateAnswer.Raw
+			if len(updateAnswer.Files) > 0 {
+				reply.Files = updateAnswer.Files
+			}
+
+			return s.save(db)
+		}
+	}
+
+	return errors.New("Target answer is not found.")
+}
+
+// AnswerAt records an answer to a given question, e.g. a reply in the
+// thread of the question. It is added to the answer as a reply if the
+// question was already answered.
+func (s *Standup) AnswerAt(db *dynamo.DB, index int, answer Answer) error {
+	if index >= len(s.Questions) {
+		return errors.New("Target question is not found.")
+	}
+	if answer.PostedAt != "" && s.HasAnswer(answer.PostedAt) {
+		return nil
+	}
+
+	switch {
+	case index < len(s.Answers) && s.Answers[index].PostedAt != "":
+		existing := &s.Answers[index]
+		existing.Replies = append(existing.Replies, Reply{
+			Text:     answer.Text,
+			Raw:      answer.Raw,
+			PostedAt: answer.PostedAt,
+			Files:    answer.Files,
+		})
+	case s.CurrentStatus() == StatusCompleted:
+		return &TransitionError{From: StatusCompleted, To: StatusAsking}
+	default:
+		for len(s.Answers) <= index {
+			// Left for the questions in between, asked already
+			s.Answers = append(s.Answers, Answer{})
+		}
+		s.Answers[index] = answer
+
+		if s.NextQuestion() >= len(s.Questions) {
+			if err := s.transition(StatusCompleted); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.save(db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AttachFiles adds files shared on their own to the latest answer.
+func (s *Standup) AttachFiles(db *dynamo.DB, files []File) error {
+	for i := len(s.Answers) - 1; i >= 0; i-- {
+		if s.Answers[i].PostedAt == "" {
+			continue
+		}
+
+		s.Answers[i].Files = append(s.Answers[i].Files, files...)
+
+		if err := s.save(db); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	return errors.New("There is no answer to attach files to.")
+}
+
+// Question returns the index of the question posted at a timestamp, or -1.
+func (s *Standup) Question(postedAt string) int {
+	for i, q := range s.Questions {
+		if q.PostedAt == postedAt {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// DeleteAnswer clears a deleted answer so that its question is asked again,
+// which takes a completed standup back to asking. A deleted reply is removed
+// from its answer, and the first reply takes the place of a deleted answer
+// which has some.
+func (s *Standup) DeleteAnswer(db *dynamo.DB, postedAt string) error {
+	for i := range s.Answers {
+		answer := &s.Answers[i]
+
+		for j, reply := range answer.Replies {
+			if reply.PostedAt == postedAt {
+				answer.Replies = append(answer.Replies[:j], answer.Replies[j+1:]...)
+				return s.save(db)
+			}
+		}
+
+		if answer.PostedAt != postedAt {
+			continue
+		}
 
-			if err := table.Put(s).Run(); err != nil {
+		if len(answer.Replies) > 0 {
+			first := answer.Replies[0]
+			answer.Text = first.Text
+			answer.Raw = first.Raw
+			answer.PostedAt = first.PostedAt
+			answer.Files = first.Files
+			answer.Replies = answer.Replies[1:]
+
+			return s.save(db)
+		}
+
+		if s.CurrentStatus() == StatusCompleted {
+			if err := s.transition(StatusAsking); err != nil {
 				return err
 			}
+		}
 
-			return nil
+		s.Answers[i] = Answer{}
+		if i < len(s.Questions) {
+			s.Questions[i].PostedAt = ""
 		}
+
+		if err := s.save(db); err != nil {
+			return err
+		}
+
+		return nil
 	}
 
 	return errors.New("Target answer is not found.")
 }
 
+// HasAnswer reports whether an answer or a reply was posted at a timestamp.
+func (s *Standup) HasAnswer(postedAt string) bool {
+	for _, answer := range s.Answers {
+		if answer.PostedAt == postedAt {
+			return true
+		}
+		for _, reply := range answer.Replies {
+			if reply.PostedAt == postedAt {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// NextQuestion returns the index of the question waiting for an answer,
+// the first one whose answer was deleted or else the one after the last
+// answer.
+func (s *Standup) NextQuestion() int {
+	for i, answer := range s.Answers {
+		if answer.Text == "" && answer.PostedAt == "" {
+			return i
+		}
+	}
+
+	return len(s.Answers)
+}
+
+// Introduce records that the intro of the standup was sent.
+func (s *Standup) Introduce(db *dynamo.DB) error {
+	s.Introduced = true
+
+	if err := s.save(db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *Standup) SentQuestion(db *dynamo.DB, questionIndex int, postedAt string) error {
-	table := db.Table(standupsTable)
+	if s.CurrentStatus() == StatusPending {
+		if err := s.transition(StatusAsking); err != nil {
+			return err
+		}
+	}
 
 	s.Questions[questionIndex].PostedAt = postedAt
 
-	if err := table.Put(s).Run(); err != nil {
+	if err := s.save(db); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (s *Standup) Finish(db *dynamo.DB, finishedAt string) error {
-	table := db.Table(standupsTable)
+// Escalate records the message escalating the blocker of the standup.
+func (s *Standup) Escalate(db *dynamo.DB, channelID string, timestamp string) error {
+	s.Escalation = &Escalation{ChannelID: channelID, Timestamp: timestamp}
+
+	if err := s.save(db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Resolve records who resolved the escalated blocker, once.
+func (s *Standup) Resolve(db *dynamo.DB, userID string) error {
+	if s.Escalation == nil {
+		return errors.New("Stand-up has no escalated blocker.")
+	}
+	if s.Escalation.ResolvedBy != "" {
+		return nil
+	}
+
+	s.Escalation.ResolvedBy = userID
+	s.Escalation.ResolvedAt = now().UTC().Format(time.RFC3339)
+
+	if err := s.save(db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SummaryTimestamp returns the timestamp of the summary posted to a
+// channel, or "" if there is none yet.
+func (s *Standup) SummaryTimestamp(channelID string) string {
+	for _, summary := range s.Summaries {
+		if summary.ChannelID == channelID {
+			return summary.Timestamp
+		}
+	}
+
+	if channelID == s.TargetChannelID {
+		// Standups posted before summaries were tracked per destination
+		return s.FinishedAt
+	}
+
+	return ""
+}
+
+// Retract forgets the summaries of the standup once they are deleted.
+func (s *Standup) Retract(db *dynamo.DB) error {
+	s.Summaries = nil
+	s.FinishedAt = ""
+
+	if err := s.save(db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Finish records the summaries posted for a completed standup. FinishedAt
+// keeps the one of the target channel.
+func (s *Standup) Finish(db *dynamo.DB, summaries []Summary) error {
+	if s.CurrentStatus() != StatusCompleted {
+		return &TransitionError{From: s.CurrentStatus(), To: StatusCompleted}
+	}
+
+	for _, summary := range summaries {
+		if summary.ChannelID == s.TargetChannelID {
+			s.FinishedAt = summary.Timestamp
+		}
+		s.Summaries = append(s.Summaries, summary)
+	}
 
-	s.FinishedAt = finishedAt
-	if err := table.Put(s).Run(); err != nil {
+	if err := s.save(db); err != nil {
 		return err
 	}
 
@@ -82,57 +501,217 @@ func (s *Standup) Finish(db *dynamo.DB, finishedAt string) error {
 }
 
 func (s *Standup) Cancel(db *dynamo.DB) error {
-	table := db.Table(standupsTable)
+	return s.close(db, StatusCanceled)
+}
+
+func (s *Standup) Skip(db *dynamo.DB) error {
+	return s.close(db, StatusSkipped)
+}
+
+// Backfill opens the standup of a past day as a late one. An expired
+// standup keeps its answers and sends the questions left again.
+func (s *Standup) Backfill(db *dynamo.DB) error {
+	s.Late = true
+
+	if s.CurrentStatus() == StatusExpired {
+		if err := s.transition(StatusAsking); err != nil {
+			return err
+		}
+
+		for i := range s.Questions {
+			if i >= len(s.Answers) || s.Answers[i].Text == "" && s.Answers[i].PostedAt == "" {
+				s.Questions[i].PostedAt = ""
+			}
+		}
+	}
+
+	if err := s.save(db); err != nil {
+		return err
+	}
 
-	var cancels []Answer
-	for range s.Questions {
-		cancels = append(cancels, Answer{Text: "none"})
+	return nil
+}
+
+// Expire closes a standup left unanswered.
+func (s *Standup) Expire(db *dynamo.DB) error {
+	return s.close(db, StatusExpired)
+}
+
+func (s *Standup) close(db *dynamo.DB, status Status) error {
+	if err := s.transition(status); err != nil {
+		return err
 	}
-	s.Answers = cancels
 
-	if err := table.Put(s).Run(); err != nil {
+	if err := s.save(db); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func Get(db *dynamo.DB, tz string, userID string, consistent bool) (*Standup, error) {
+// Today returns the date of today in a timezone, as used for the date of
+// standups.
+func Today(tz string) (string, error) {
+	return DateAt(tz, time.Now())
+}
+
+// DateAt returns the date of t in a timezone.
+func DateAt(tz string, t time.Time) (string, error) {
 	locate, err := time.LoadLocation(tz)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
+	return t.In(locate).Format("2006-01-02"), nil
+}
+
+func Get(db *dynamo.DB, userID string, date string, consistent bool) (*Standup, error) {
 	table := db.Table(standupsTable)
-	today := time.Now().In(locate).Format("2006-01-02")
 
 	var s Standup
-	if err = table.Get("user_id", userID).Range("date", dynamo.Equal, today).Consistent(consistent).One(&s); err != nil {
+	if err := table.Get("user_id", userID).Range("date", dynamo.Equal, date).Consistent(consistent).One(&s); err != nil {
 		return nil, err
 	}
+	s.Status = s.CurrentStatus()
 
 	return &s, nil
 }
 
-func Initial(db *dynamo.DB, tz string, userID string, questions []Question, targetChannelID string) error {
-	locate, err := time.LoadLocation(tz)
+// Since returns the standups of a user from a date on, the most recent
+// first.
+func Since(db *dynamo.DB, userID string, date string, consistent bool) ([]Standup, error) {
+	table := db.Table(standupsTable)
+
+	var standups []Standup
+	err := table.Get("user_id", userID).
+		Range("date", dynamo.GreaterOrEqual, date).
+		Order(dynamo.Descending).
+		Consistent(consistent).
+		All(&standups)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	for i := range standups {
+		standups[i].Status = standups[i].CurrentStatus()
+	}
+
+	return standups, nil
+}
+
+// Previous returns the standups of the user before the one of s, within
+// BackfillDays, the most recent first.
+func (s *Standup) Previous(db *dynamo.DB) ([]Standup, error) {
+	date, err := time.Parse("2006-01-02", s.Date)
+	if err != nil {
+		return nil, err
 	}
+	since := date.AddDate(0, 0, -BackfillDays).Format("2006-01-02")
 
 	table := db.Table(standupsTable)
-	today := time.Now().In(locate).Format("2006-01-02")
 
-	s := Standup{
+	var standups []Standup
+	err = table.Get("user_id", s.UserID).
+		Range("date", dynamo.Between, since, s.Date).
+		Order(dynamo.Descending).
+		All(&standups)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := standups[:0]
+	for _, st := range standups {
+		if st.Date != s.Date {
+			previous = append(previous, st)
+		}
+	}
+
+	return previous, nil
+}
+
+// LastAnswerAt returns the most recent answer to the question of an index
+// among standups sorted from the most recent, or "".
+func LastAnswerAt(standups []Standup, index int) string {
+	for _, s := range standups {
+		if index < len(s.Answers) && s.Answers[index].Text != "" {
+			return s.Answers[index].FullText()
+		}
+	}
+
+	return ""
+}
+
+// LastAnswerTo returns the most recent answer to a question among standups
+// sorted from the most recent, or "".
+func LastAnswerTo(standups []Standup, question string) string {
+	for _, s := range standups {
+		for i, q := range s.Questions {
+			if q.Text == question && i < len(s.Answers) && s.Answers[i].Text != "" {
+				return s.Answers[i].FullText()
+			}
+		}
+	}
+
+	return ""
+}
+
+func New(tz string, userID string, questions []Question, targetChannelID string) (*Standup, error) {
+	today, err := Today(tz)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewOn(today, userID, questions, targetChannelID), nil
+}
+
+// NewOn returns a pending standup of a given date.
+func NewOn(date string, userID string, questions []Question, targetChannelID string) *Standup {
+	return &Standup{
 		UserID:          userID,
-		Date:            today,
+		Date:            date,
 		Questions:       questions,
 		Answers:         []Answer{},
 		TargetChannelID: targetChannelID,
+		Status:          StatusPending,
+		Transitions: []Transition{
+			Transition{To: StatusPending, At: now().UTC().Format(time.RFC3339)},
+		},
+	}
+}
+
+func Initial(db *dynamo.DB, tz string, userID string, questions []Question, targetChannelID string) error {
+	s, err := New(tz, userID, questions, targetChannelID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.save(db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// BatchInitial writes many standups at once, 25 items per request.
+func BatchInitial(db *dynamo.DB, standups []*Standup) error {
+	if len(standups) == 0 {
+		return nil
+	}
+
+	table := db.Table(standupsTable)
+
+	items := make([]interface{}, len(standups))
+	for i, s := range standups {
+		items[i] = s
 	}
-	if err := table.Put(s).Run(); err != nil {
+
+	if _, err := table.Batch("user_id", "date").Write().Put(items...).Run(); err != nil {
 		return err
 	}
 
+	for _, s := range standups {
+		notify(*s)
+	}
+
 	return nil
 }
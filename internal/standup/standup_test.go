@@ -41,10 +41,12 @@ func TestAppendAnswerSuccess(t *testing.T) {
 	}
 
 	standup := &Standup{
-		UserID: "user",
+		UserID:    "user",
+		Questions: []Question{Question{Text: "q1"}, Question{Text: "q2"}},
 		Answers: []Answer{
 			Answer{Text: "answer1"},
 		},
+		Status: StatusAsking,
 	}
 
 	mockedClient := &mockedDynamo{Resp: want}
@@ -54,22 +56,27 @@ func TestAppendAnswerSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("%q", err)
 	}
+
+	if standup.Status != StatusCompleted {
+		t.Fatalf("Want %s, got %s", StatusCompleted, standup.Status)
+	}
 }
 
 func TestCancelSuccess(t *testing.T) {
 	want := &Standup{
 		UserID: "user",
 		Answers: []Answer{
-			Answer{Text: "none"},
-			Answer{Text: "none"},
+			Answer{Text: "answer1"},
 		},
 	}
 
 	standup := &Standup{
-		UserID: "user",
+		UserID:    "user",
+		Questions: []Question{Question{Text: "q1"}, Question{Text: "q2"}},
 		Answers: []Answer{
 			Answer{Text: "answer1"},
 		},
+		Status: StatusAsking,
 	}
 
 	mockedClient := &mockedDynamo{Resp: want}
@@ -79,7 +86,273 @@ func TestCancelSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatalf("%q", err)
 	}
+
+	if standup.Status != StatusCanceled || len(standup.Transitions) != 1 {
+		t.Fatalf("Unexpected standup: %+v", standup)
+	}
 }
 
 func TestGetSuccess(t *testing.T)     {}
 func TestInitialSuccess(t *testing.T) {}
+
+type mockedBatchDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	Wrote []string
+}
+
+func (m *mockedBatchDynamo) BatchWriteItemWithContext(context aws.Context, input *dynamodb.BatchWriteItemInput, options ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	for _, requests := range input.RequestItems {
+		if len(requests) > 25 {
+			return nil, fmt.Errorf("Too many write requests: %d", len(requests))
+		}
+
+		for _, r := range requests {
+			m.Wrote = append(m.Wrote, *r.PutRequest.Item["user_id"].S)
+		}
+	}
+
+	return &dynamodb.BatchWriteItemOutput{}, nil
+}
+
+func TestNewSuccess(t *testing.T) {
+	s, err := New("Asia/Tokyo", "user", []Question{Question{Text: "q1"}}, "channel")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if s.UserID != "user" || s.TargetChannelID != "channel" || s.Date == "" {
+		t.Fatalf("Unexpected standup: %+v", s)
+	}
+}
+
+func TestBatchInitialSuccess(t *testing.T) {
+	var standups []*Standup
+	for i := 0; i < 30; i++ {
+		standups = append(standups, &Standup{UserID: fmt.Sprintf("user%d", i), Date: "2018-09-01"})
+	}
+
+	mockedClient := &mockedBatchDynamo{}
+	db := dynamo.NewFromIface(mockedClient)
+
+	if err := BatchInitial(db, standups); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(mockedClient.Wrote) != len(standups) {
+		t.Fatalf("Want %d items written, got %d", len(standups), len(mockedClient.Wrote))
+	}
+}
+
+func TestSubscribeSuccess(t *testing.T) {
+	var got []Standup
+	Subscribe(func(s Standup) {
+		if s.UserID == "subscribed" {
+			got = append(got, s)
+		}
+	})
+
+	standup := &Standup{UserID: "subscribed"}
+
+	mockedClient := &mockedDynamo{Resp: &Standup{}}
+	db := dynamo.NewFromIface(mockedClient)
+
+	if err := standup.AppendAnswer(db, Answer{Text: "answer1"}); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(got) != 1 || len(got[0].Answers) != 1 {
+		t.Fatalf("Want 1 notification with the answer, got %+v", got)
+	}
+}
+
+func TestLastAnswerAt(t *testing.T) {
+	standups := []Standup{
+		Standup{Date: "2018-09-04", Answers: []Answer{Answer{Text: "Reviews"}}},
+		Standup{Date: "2018-09-03", Answers: []Answer{Answer{Text: "Release"}, Answer{Text: "Docs"}}},
+	}
+
+	if got := LastAnswerAt(standups, 1); got != "Docs" {
+		t.Fatalf("Want Docs, got %q", got)
+	}
+	if got := LastAnswerAt(standups, 2); got != "" {
+		t.Fatalf("Want nothing, got %q", got)
+	}
+}
+
+func TestLastAnswerTo(t *testing.T) {
+	standups := []Standup{
+		Standup{
+			Questions: []Question{Question{Text: "On call?"}},
+			Answers:   []Answer{},
+		},
+		Standup{
+			Questions: []Question{Question{Text: "Yesterday?"}, Question{Text: "On call?"}},
+			Answers:   []Answer{Answer{Text: "Release"}, Answer{Text: "Primary this week"}},
+		},
+	}
+
+	if got := LastAnswerTo(standups, "On call?"); got != "Primary this week" {
+		t.Fatalf("Want the previous answer, got %q", got)
+	}
+	if got := LastAnswerTo(standups, "Today?"); got != "" {
+		t.Fatalf("Want nothing, got %q", got)
+	}
+}
+
+func TestDeleteAnswerAsksAgain(t *testing.T) {
+	s := &Standup{
+		UserID:    "user",
+		Questions: []Question{Question{Text: "q1", PostedAt: "1.0"}, Question{Text: "q2", PostedAt: "2.0"}},
+		Answers:   []Answer{Answer{Text: "a1", PostedAt: "1.5"}, Answer{Text: "a2", PostedAt: "2.5"}},
+		Status:    StatusCompleted,
+	}
+
+	db := dynamo.NewFromIface(&mockedDynamo{Resp: &Standup{}})
+
+	if err := s.DeleteAnswer(db, "1.5"); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if s.Status != StatusAsking || s.NextQuestion() != 0 || s.Questions[0].PostedAt != "" {
+		t.Fatalf("Unexpected standup: %+v", s)
+	}
+
+	if err := s.AppendAnswer(db, Answer{Text: "a1 again", PostedAt: "3.0"}); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if s.Status != StatusCompleted || len(s.Answers) != 2 || s.Answers[0].Text != "a1 again" {
+		t.Fatalf("Unexpected standup: %+v", s)
+	}
+}
+
+func TestAnswerAtOutOfOrder(t *testing.T) {
+	s := &Standup{
+		UserID: "user",
+		Questions: []Question{
+			Question{Text: "q1", PostedAt: "1.0"},
+			Question{Text: "q2", PostedAt: "2.0"},
+			Question{Text: "q3", PostedAt: "3.0"},
+		},
+		Answers: []Answer{Answer{Text: "a1", PostedAt: "1.5"}},
+		Status:  StatusAsking,
+	}
+
+	db := dynamo.NewFromIface(&mockedDynamo{Resp: &Standup{}})
+
+	if err := s.AnswerAt(db, 2, Answer{Text: "a3", PostedAt: "3.5"}); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if s.Status != StatusAsking || s.NextQuestion() != 1 || s.Answers[2].Text != "a3" {
+		t.Fatalf("Unexpected standup: %+v", s)
+	}
+
+	if err := s.AnswerAt(db, 0, Answer{Text: "and more", PostedAt: "4.0", Files: []File{File{ID: "F1"}}}); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if s.Answers[0].FullText() != "a1\nand more" || s.Answers[0].PostedAt != "1.5" || len(s.Answers[0].AllFiles()) != 1 {
+		t.Fatalf("Unexpected answer: %+v", s.Answers[0])
+	}
+
+	if err := s.UpdateAnswer(db, Answer{Text: "and even more", PostedAt: "4.0", EditedAt: "4.5"}); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if s.Answers[0].FullText() != "a1\nand even more" || s.Answers[0].EditedAt != "4.5" || len(s.Answers[0].Revisions) != 1 {
+		t.Fatalf("Unexpected answer: %+v", s.Answers[0])
+	}
+
+	if err := s.DeleteAnswer(db, "1.5"); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if s.Answers[0].FullText() != "and even more" || s.Answers[0].PostedAt != "4.0" || len(s.Answers[0].Replies) != 0 {
+		t.Fatalf("Want the reply to take the place of the answer, got %+v", s.Answers[0])
+	}
+
+	if err := s.AppendAnswer(db, Answer{Text: "a2", PostedAt: "5.0"}); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if s.Status != StatusCompleted || s.Answers[1].Text != "a2" {
+		t.Fatalf("Unexpected standup: %+v", s)
+	}
+}
+
+func TestAppendAnswerIgnoresRedelivery(t *testing.T) {
+	s := &Standup{
+		UserID:    "user",
+		Questions: []Question{Question{Text: "q1"}, Question{Text: "q2"}},
+		Answers:   []Answer{Answer{Text: "a1", PostedAt: "1.5"}},
+		Status:    StatusAsking,
+	}
+
+	db := dynamo.NewFromIface(&mockedDynamo{Resp: &Standup{}})
+
+	if err := s.AppendAnswer(db, Answer{Text: "a1", PostedAt: "1.5"}); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if len(s.Answers) != 1 || s.Status != StatusAsking {
+		t.Fatalf("Unexpected standup: %+v", s)
+	}
+}
+
+func TestBackfillKeepsAnswers(t *testing.T) {
+	s := &Standup{
+		UserID:    "user",
+		Questions: []Question{Question{Text: "q1", PostedAt: "1.0"}, Question{Text: "q2", PostedAt: "2.0"}},
+		Answers:   []Answer{Answer{Text: "a1", PostedAt: "1.5"}},
+		Status:    StatusExpired,
+	}
+
+	db := dynamo.NewFromIface(&mockedDynamo{Resp: &Standup{}})
+
+	if err := s.Backfill(db); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if s.Status != StatusAsking || !s.Late || len(s.Answers) != 1 || s.Answers[0].Text != "a1" {
+		t.Fatalf("Unexpected standup: %+v", s)
+	}
+	// Sent again rather than left waiting for an answer
+	if s.Questions[0].PostedAt != "1.0" || s.Questions[1].PostedAt != "" {
+		t.Fatalf("Unexpected questions: %+v", s.Questions)
+	}
+}
+
+type conditionalDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	Version string
+	Inputs  []*dynamodb.PutItemInput
+}
+
+// PutItemWithContext fails unless the condition names the stored version.
+func (m *conditionalDynamo) PutItemWithContext(context aws.Context, input *dynamodb.PutItemInput, options ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.Inputs = append(m.Inputs, input)
+
+	for _, v := range input.ExpressionAttributeValues {
+		if aws.StringValue(v.N) != m.Version {
+			return nil, fmt.Errorf("ConditionalCheckFailedException")
+		}
+	}
+	m.Version = aws.StringValue(input.Item["version"].N)
+
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestSaveFailsOnStaleStandup(t *testing.T) {
+	mocked := &conditionalDynamo{Version: "0"}
+	db := dynamo.NewFromIface(mocked)
+
+	s := &Standup{UserID: "user", Questions: []Question{Question{Text: "q1"}}, Status: StatusPending}
+	stale := *s
+
+	if err := s.SentQuestion(db, 0, "1.0"); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if s.Version != 1 || aws.StringValue(mocked.Inputs[0].ConditionExpression) == "" {
+		t.Fatalf("Want a conditional write of version 1, got %+v", mocked.Inputs[0])
+	}
+
+	if err := stale.Cancel(db); err == nil {
+		t.Fatal("Want an error writing a stale standup")
+	}
+	if stale.Version != 0 {
+		t.Fatalf("Want the version to be kept, got %d", stale.Version)
+	}
+}
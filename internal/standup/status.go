@@ -0,0 +1,102 @@
+package standup
+
+import (
+	"fmt"
+	"time"
+)
+
+// Status is where a standup is in its lifecycle.
+//
+//	pending -> asking <-> completed
+//	   |         |
+//	   +---------+-> canceled, skipped, expired
+//
+// A completed standup goes back to asking when an answer is deleted, and an
+// expired one when it is backfilled.
+type Status string
+
+const (
+	// StatusPending is a standup whose first question hasn't been sent.
+	StatusPending Status = "pending"
+	// StatusAsking is a standup waiting for answers.
+	StatusAsking Status = "asking"
+	// StatusCompleted is a standup with every question answered. Answers
+	// can still be edited, which updates the summary.
+	StatusCompleted Status = "completed"
+	// StatusCanceled is a standup the member has canceled.
+	StatusCanceled Status = "canceled"
+	// StatusSkipped is a standup the member won't attend, e.g. on a day off.
+	StatusSkipped Status = "skipped"
+	// StatusExpired is a standup left unanswered until the next one.
+	StatusExpired Status = "expired"
+)
+
+var transitions = map[Status][]Status{
+	StatusPending:   {StatusAsking, StatusCanceled, StatusSkipped, StatusExpired},
+	StatusAsking:    {StatusCompleted, StatusCanceled, StatusSkipped, StatusExpired},
+	StatusCompleted: {StatusAsking},
+	StatusExpired:   {StatusAsking},
+}
+
+// Transition records when a standup changed its status.
+type Transition struct {
+	From Status `dynamo:"from"`
+	To   Status `dynamo:"to"`
+	At   string `dynamo:"at"`
+}
+
+// TransitionError is returned when a standup can't move to a status from
+// its current one.
+type TransitionError struct {
+	From Status
+	To   Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("standup: can't change status from %s to %s", e.From, e.To)
+}
+
+var now = time.Now
+
+// Open reports whether the standup still waits for answers.
+func (s *Standup) Open() bool {
+	status := s.CurrentStatus()
+	return status == StatusPending || status == StatusAsking
+}
+
+// CurrentStatus returns the status of the standup, inferred from its
+// answers for standups written before statuses were recorded.
+func (s *Standup) CurrentStatus() Status {
+	if s.Status != "" {
+		return s.Status
+	}
+
+	switch {
+	case s.FinishedAt != "" || (len(s.Questions) > 0 && len(s.Answers) >= len(s.Questions)):
+		return StatusCompleted
+	case len(s.Questions) > 0 && s.Questions[0].PostedAt != "":
+		return StatusAsking
+	default:
+		return StatusPending
+	}
+}
+
+// transition moves the standup to a status, without saving it.
+func (s *Standup) transition(to Status) error {
+	from := s.CurrentStatus()
+
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			s.Status = to
+			s.Transitions = append(s.Transitions, Transition{
+				From: from,
+				To:   to,
+				At:   now().UTC().Format(time.RFC3339),
+			})
+
+			return nil
+		}
+	}
+
+	return &TransitionError{From: from, To: to}
+}
@@ -0,0 +1,60 @@
+package standup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransitionRecordsTime(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2018, 9, 3, 1, 0, 0, 0, time.UTC) }
+
+	s := &Standup{Status: StatusPending}
+	if err := s.transition(StatusAsking); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	want := Transition{From: StatusPending, To: StatusAsking, At: "2018-09-03T01:00:00Z"}
+	if s.Status != StatusAsking || len(s.Transitions) != 1 || s.Transitions[0] != want {
+		t.Fatalf("Unexpected standup: %+v", s)
+	}
+}
+
+func TestTransitionRejectsClosedStandup(t *testing.T) {
+	for _, from := range []Status{StatusCanceled, StatusSkipped} {
+		s := &Standup{Status: from}
+
+		err := s.transition(StatusAsking)
+		if _, ok := err.(*TransitionError); !ok {
+			t.Fatalf("Want *TransitionError from %s, got %v", from, err)
+		}
+		if s.Status != from || len(s.Transitions) != 0 {
+			t.Fatalf("Unexpected standup: %+v", s)
+		}
+	}
+}
+
+func TestCurrentStatusOfLegacyStandup(t *testing.T) {
+	tests := []struct {
+		standup Standup
+		want    Status
+	}{
+		{Standup{Questions: []Question{{Text: "q1"}}}, StatusPending},
+		{Standup{Questions: []Question{{Text: "q1", PostedAt: "1.0"}}}, StatusAsking},
+		{Standup{Questions: []Question{{Text: "q1", PostedAt: "1.0"}}, Answers: []Answer{{Text: "a1"}}}, StatusCompleted},
+		{Standup{Questions: []Question{{Text: "q1"}}, FinishedAt: "2.0"}, StatusCompleted},
+	}
+
+	for _, test := range tests {
+		if got := test.standup.CurrentStatus(); got != test.want {
+			t.Fatalf("Want %s, got %s for %+v", test.want, got, test.standup)
+		}
+	}
+}
+
+func TestTransitionReopensCompletedStandup(t *testing.T) {
+	s := &Standup{Status: StatusCompleted}
+	if err := s.transition(StatusAsking); err != nil {
+		t.Fatalf("%q", err)
+	}
+}
@@ -0,0 +1,236 @@
+package start
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/blocker"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/schedule"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/thread"
+	"github.com/tsub/serverless-daily-standup-bot/internal/usercache"
+	"github.com/tsub/serverless-daily-standup-bot/internal/util"
+)
+
+// concurrency bounds the number of members processed at the same time,
+// which keeps us under the Slack tier limit of users.info.
+var concurrency = 10
+
+func init() {
+	if v, err := strconv.Atoi(os.Getenv("START_CONCURRENCY")); err == nil && v > 0 {
+		concurrency = v
+	}
+}
+
+// Run creates today's standups of the members of a setting, which sends
+// them the first question.
+func Run(ctx context.Context, input schedule.Input) error {
+	if input.TargetChannelID == "" {
+		log.Println("There is no target_channel_id")
+		return nil
+	}
+
+	db := dynamo.New(session.New())
+
+	s, err := setting.Get(db, input.TargetChannelID)
+	if err != nil {
+		return err
+	}
+
+	if !s.Active() {
+		log.Printf("Skip since the setting of %s is paused or disabled.", s.TargetChannelID)
+		return nil
+	}
+
+	teamID := input.TeamID
+	if teamID == "" {
+		// Rules created before team_id was part of the input
+		teamID = s.TeamID
+	}
+
+	botcl, cl, err := installation.Clients(db, teamID)
+	if err != nil {
+		return err
+	}
+
+	questions := make([]standup.Question, len(s.Questions))
+	for i, text := range s.Questions {
+		questions[i] = standup.Question{Text: text}
+	}
+
+	// Members with an open blocker are asked whether it is still there
+	followUps := map[string]string{}
+	if s.TracksBlockers() {
+		blockers, err := blocker.Open(db, s.TargetChannelID)
+		if err != nil {
+			return err
+		}
+		for i := range blockers {
+			followUps[blockers[i].UserID] = blockers[i].Question()
+		}
+	}
+
+	var mu sync.Mutex
+	var standups []*standup.Standup
+
+	prepareErr := util.Each(concurrency, s.UserIDs, func(userID string) error {
+		resp, err := usercache.Get(ctx, db, cl, userID)
+		if err != nil {
+			return fmt.Errorf("user %s: %s", userID, err)
+		}
+
+		today, err := standup.Today(resp.TZ)
+		if err != nil {
+			return fmt.Errorf("user %s: %s", userID, err)
+		}
+
+		_, err = standup.Get(db, userID, today, false)
+		if err == nil {
+			return nil
+		}
+		if err != dynamo.ErrNotFound {
+			return fmt.Errorf("user %s: %s", userID, err)
+		}
+
+		qs := questions
+		if text, ok := followUps[userID]; ok {
+			qs = append([]standup.Question(nil), questions...)
+			qs[s.BlockerIndex()].FollowUp = text
+		}
+
+		st, err := standup.New(resp.TZ, userID, qs, s.TargetChannelID)
+		if err != nil {
+			return fmt.Errorf("user %s: %s", userID, err)
+		}
+		st.TeamID = teamID
+
+		if err := expire(db, userID, st.Date); err != nil {
+			return fmt.Errorf("user %s: %s", userID, err)
+		}
+
+		mu.Lock()
+		standups = append(standups, st)
+		mu.Unlock()
+
+		return nil
+	})
+	if prepareErr != nil {
+		// Keep going so that one broken member doesn't block everyone else
+		log.Printf("failed to prepare some members: %s", prepareErr)
+	}
+
+	if len(standups) == 0 {
+		if prepareErr == nil {
+			log.Println("Skip since it has already been executed today.")
+		}
+		return prepareErr
+	}
+
+	if s.Threaded {
+		if err := openThreads(ctx, db, botcl, s.TargetChannelID, standups); err != nil {
+			return err
+		}
+	}
+
+	if err := standup.BatchInitial(db, standups); err != nil {
+		return err
+	}
+
+	// Fail the run so that a retry starts the members left out
+	return prepareErr
+}
+
+// openThreads posts the parent message of the day's summaries, one per
+// date as members in other timezones may already be on another day. The
+// thread is recorded before its message is posted, so that a retry after a
+// failed write doesn't post a second parent.
+func openThreads(ctx context.Context, db *dynamo.DB, cl slackapi.Client, targetChannelID string, standups []*standup.Standup) error {
+	byDate := map[string][]*standup.Standup{}
+	for _, st := range standups {
+		byDate[st.Date] = append(byDate[st.Date], st)
+	}
+
+	for date, sts := range byDate {
+		var userIDs []string
+		for _, st := range sts {
+			userIDs = append(userIDs, st.UserID)
+		}
+
+		t, err := thread.Get(db, targetChannelID, date)
+		switch {
+		case err == dynamo.ErrNotFound:
+			t = &thread.Thread{TargetChannelID: targetChannelID, Date: date, UserIDs: userIDs}
+
+			if err := thread.Create(db, *t); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			// Started again on the same day
+			for _, userID := range userIDs {
+				if t, err = thread.Join(db, targetChannelID, date, userID); err != nil {
+					return err
+				}
+			}
+		}
+
+		if t.Timestamp == "" {
+			// Not posted yet, or the post failed on a previous run
+			ts, err := cl.PostMessage(ctx, targetChannelID, slackapi.Message{Text: t.Text()})
+			if err != nil {
+				return err
+			}
+
+			if t, err = thread.SetTimestamp(db, targetChannelID, date, ts); err != nil {
+				return err
+			}
+		} else if err := cl.UpdateMessage(ctx, targetChannelID, t.Timestamp, slackapi.Message{Text: t.Text()}); err != nil {
+			return err
+		}
+
+		for _, st := range sts {
+			st.ThreadTS = t.Timestamp
+		}
+	}
+
+	return nil
+}
+
+// expire closes the previous standups of a member left open, including
+// backfilled ones, so that answers only go to the new one.
+func expire(db *dynamo.DB, userID string, date string) error {
+	today, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return err
+	}
+	since := today.AddDate(0, 0, -standup.BackfillDays-1).Format("2006-01-02")
+
+	standups, err := standup.Since(db, userID, since, false)
+	if err != nil {
+		return err
+	}
+
+	for i := range standups {
+		prev := &standups[i]
+		if prev.Date >= date || !prev.Open() {
+			continue
+		}
+
+		if err := prev.Expire(db); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
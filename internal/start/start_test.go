@@ -0,0 +1,172 @@
+package start
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/thread"
+)
+
+// threadsDynamo keeps a single thread, set by a put and changed by updates
+// setting its timestamp or adding members.
+type threadsDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	Thread *thread.Thread
+}
+
+func (m *threadsDynamo) item() (map[string]*dynamodb.AttributeValue, error) {
+	if m.Thread == nil {
+		return nil, nil
+	}
+
+	return dynamo.MarshalItem(m.Thread)
+}
+
+func (m *threadsDynamo) GetItemWithContext(context aws.Context, input *dynamodb.GetItemInput, options ...request.Option) (*dynamodb.GetItemOutput, error) {
+	item, err := m.item()
+	return &dynamodb.GetItemOutput{Item: item}, err
+}
+
+func (m *threadsDynamo) QueryWithContext(context aws.Context, input *dynamodb.QueryInput, options ...request.Option) (*dynamodb.QueryOutput, error) {
+	item, err := m.item()
+	if item == nil || err != nil {
+		return &dynamodb.QueryOutput{Count: aws.Int64(0)}, err
+	}
+
+	return &dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{item}, Count: aws.Int64(1)}, nil
+}
+
+func (m *threadsDynamo) PutItemWithContext(context aws.Context, input *dynamodb.PutItemInput, options ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.Thread = &thread.Thread{}
+	if err := dynamo.UnmarshalItem(input.Item, m.Thread); err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *threadsDynamo) UpdateItemWithContext(context aws.Context, input *dynamodb.UpdateItemInput, options ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	for _, v := range input.ExpressionAttributeValues {
+		switch {
+		case v.S != nil:
+			m.Thread.Timestamp = *v.S
+		case v.SS != nil:
+			for _, userID := range v.SS {
+				if !contains(m.Thread.UserIDs, *userID) {
+					m.Thread.UserIDs = append(m.Thread.UserIDs, *userID)
+				}
+			}
+		}
+	}
+
+	item, err := m.item()
+	return &dynamodb.UpdateItemOutput{Attributes: item}, err
+}
+
+func contains(vs []string, v string) bool {
+	for _, s := range vs {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// failingClient fails to post while fail is set.
+type failingClient struct {
+	*slackapi.Fake
+	fail bool
+}
+
+func (c *failingClient) PostMessage(ctx context.Context, channelID string, msg slackapi.Message) (string, error) {
+	if c.fail {
+		return "", &slackapi.Error{Method: "chat.postMessage", Code: "fatal_error"}
+	}
+
+	return c.Fake.PostMessage(ctx, channelID, msg)
+}
+
+func newStandups(userIDs ...string) []*standup.Standup {
+	var standups []*standup.Standup
+	for _, userID := range userIDs {
+		standups = append(standups, standup.NewOn("2019-01-01", userID, nil, "C1"))
+	}
+
+	return standups
+}
+
+func TestOpenThreadsPostsParent(t *testing.T) {
+	mocked := &threadsDynamo{}
+	db := dynamo.NewFromIface(mocked)
+	cl := &slackapi.Fake{}
+
+	standups := newStandups("U1", "U2")
+	if err := openThreads(context.Background(), db, cl, "C1", standups); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(cl.Posted) != 1 || cl.Posted[0].ChannelID != "C1" || !strings.HasSuffix(cl.Posted[0].Message.Text, "0/2 done") {
+		t.Fatalf("Want the parent message to be posted, got %+v", cl.Posted)
+	}
+	ts := cl.Posted[0].Timestamp
+	if mocked.Thread == nil || mocked.Thread.Timestamp != ts {
+		t.Fatalf("Want the thread to be recorded at %s, got %+v", ts, mocked.Thread)
+	}
+	for _, st := range standups {
+		if st.ThreadTS != ts {
+			t.Fatalf("Want the standup in the thread, got %+v", st)
+		}
+	}
+}
+
+func TestOpenThreadsUpdatesParentOnRestart(t *testing.T) {
+	mocked := &threadsDynamo{Thread: &thread.Thread{TargetChannelID: "C1", Date: "2019-01-01", Timestamp: "0.5", UserIDs: []string{"U1"}}}
+	db := dynamo.NewFromIface(mocked)
+	cl := &slackapi.Fake{}
+
+	standups := newStandups("U2")
+	if err := openThreads(context.Background(), db, cl, "C1", standups); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(cl.Posted) != 0 {
+		t.Fatalf("Want no second parent, got %+v", cl.Posted)
+	}
+	if len(cl.Updated) != 1 || cl.Updated[0].Timestamp != "0.5" || !strings.HasSuffix(cl.Updated[0].Message.Text, "0/2 done") {
+		t.Fatalf("Want the parent message to be updated, got %+v", cl.Updated)
+	}
+	if standups[0].ThreadTS != "0.5" {
+		t.Fatalf("Want the standup in the thread, got %+v", standups[0])
+	}
+}
+
+func TestOpenThreadsPostsParentOnceAfterFailure(t *testing.T) {
+	mocked := &threadsDynamo{}
+	db := dynamo.NewFromIface(mocked)
+	cl := &failingClient{Fake: &slackapi.Fake{}, fail: true}
+
+	if err := openThreads(context.Background(), db, cl, "C1", newStandups("U1")); err == nil {
+		t.Fatal("Want the error of the post")
+	}
+
+	cl.fail = false
+	if err := openThreads(context.Background(), db, cl, "C1", newStandups("U1")); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if err := openThreads(context.Background(), db, cl, "C1", newStandups("U1")); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(cl.Posted) != 1 || mocked.Thread.Timestamp != cl.Posted[0].Timestamp {
+		t.Fatalf("Want a single parent message, got %+v", cl.Posted)
+	}
+}
@@ -0,0 +1,106 @@
+package summary
+
+import (
+	"fmt"
+	"text/template"
+	"text/template/parse"
+)
+
+// Budget of a template, checked before it runs since its execution can't
+// be interrupted. Ranges only go over the data, at most one inside another,
+// so that the work grows with the square of the answers at worst.
+const (
+	maxNodes      = 2000
+	maxRangeDepth = 2
+)
+
+// checkBudget walks the parse tree of a template, following the templates
+// it calls, and rejects one which could run for long.
+func checkBudget(t *template.Template) error {
+	b := &budget{t: t, calling: map[string]bool{}}
+
+	return b.walk(t.Tree.Root, 0)
+}
+
+type budget struct {
+	t       *template.Template
+	nodes   int
+	calling map[string]bool
+}
+
+func (b *budget) walk(node parse.Node, depth int) error {
+	if list, ok := node.(*parse.ListNode); ok && list == nil {
+		// A missing else branch
+		return nil
+	}
+
+	b.nodes++
+	if b.nodes > maxNodes {
+		return fmt.Errorf("template is over %d nodes", maxNodes)
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			if err := b.walk(child, depth); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return b.branches(n.List, n.ElseList, depth)
+	case *parse.WithNode:
+		return b.branches(n.List, n.ElseList, depth)
+	case *parse.RangeNode:
+		if depth >= maxRangeDepth {
+			return fmt.Errorf("template nests more than %d ranges", maxRangeDepth)
+		}
+		if !rangesOverData(n.Pipe) {
+			return fmt.Errorf("template ranges over %s, only fields and variables are allowed", n.Pipe)
+		}
+
+		if err := b.walk(n.List, depth+1); err != nil {
+			return err
+		}
+		return b.walk(n.ElseList, depth)
+	case *parse.TemplateNode:
+		if b.calling[n.Name] {
+			return fmt.Errorf("template %q calls itself", n.Name)
+		}
+
+		called := b.t.Lookup(n.Name)
+		if called == nil || called.Tree == nil {
+			// Fails to execute
+			return nil
+		}
+
+		b.calling[n.Name] = true
+		defer delete(b.calling, n.Name)
+
+		return b.walk(called.Tree.Root, depth)
+	}
+
+	return nil
+}
+
+func (b *budget) branches(list *parse.ListNode, elseList *parse.ListNode, depth int) error {
+	if err := b.walk(list, depth); err != nil {
+		return err
+	}
+
+	return b.walk(elseList, depth)
+}
+
+// rangesOverData reports whether a range goes over a field or a variable,
+// which are bounded by the data, rather than e.g. a number.
+func rangesOverData(pipe *parse.PipeNode) bool {
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+
+	switch pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode, *parse.VariableNode, *parse.DotNode, *parse.ChainNode:
+		return true
+	}
+
+	return false
+}
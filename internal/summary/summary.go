@@ -0,0 +1,478 @@
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/tsub/serverless-daily-standup-bot/internal/mrkdwn"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/usercache"
+)
+
+// maxOutput bounds what a template can produce, well over what Slack
+// accepts in a message.
+const maxOutput = 40000
+
+// Data is what summary templates are executed with.
+type Data struct {
+	User   User
+	Date   string
+	Status string
+	Late   bool
+	// Threaded is set when the summary is a reply in the thread of its day.
+	Threaded bool
+	Items    []Item
+}
+
+type User struct {
+	ID    string
+	Name  string
+	Image string
+}
+
+// Item is a question with its answer, "none" when the member skipped it.
+type Item struct {
+	Index    int
+	Question string
+	Answer   string
+	Blocker  bool
+	// Plain is the answer without Slack markup, e.g. for exports.
+	Plain string
+	// Edited is set when the answer was edited after the summary was
+	// posted.
+	Edited bool
+	Files  []File
+}
+
+// File is a file shared with an answer.
+type File struct {
+	Name string
+	URL  string
+}
+
+// NewData returns the template data of a standup.
+func NewData(s *standup.Standup, u *usercache.User) Data {
+	d := Data{
+		User:     User{ID: s.UserID, Name: u.RealName, Image: u.Image32},
+		Date:     s.Date,
+		Status:   string(s.CurrentStatus()),
+		Late:     s.Late,
+		Threaded: s.ThreadTS != "",
+	}
+
+	for i, q := range s.Questions {
+		item := Item{Index: i, Question: q.Text}
+		if i < len(s.Answers) {
+			item.Answer = s.Answers[i].FullText()
+			item.Plain = mrkdwn.Plain(item.Answer, nil)
+			item.Edited = after(s.Answers[i].EditedAt, s.SummaryTimestamp(s.TargetChannelID))
+			for _, f := range s.Answers[i].AllFiles() {
+				item.Files = append(item.Files, File{Name: f.Name, URL: f.Permalink})
+			}
+		}
+		d.Items = append(d.Items, item)
+	}
+
+	return d
+}
+
+// Mentions returns the users mentioned in the answers.
+func (d Data) Mentions() []string {
+	var answers []string
+	for _, item := range d.Items {
+		answers = append(answers, item.Answer)
+	}
+
+	return mrkdwn.Mentions(strings.Join(answers, "\n"))
+}
+
+// Resolve shows the users mentioned in the answers by name, so that posting
+// the summary doesn't notify them.
+func (d *Data) Resolve(names map[string]string) {
+	for i := range d.Items {
+		d.Items[i].Plain = mrkdwn.Plain(d.Items[i].Answer, names)
+		d.Items[i].Answer = mrkdwn.ResolveMentions(d.Items[i].Answer, names)
+	}
+}
+
+// after reports whether the Slack timestamp a is after b, false when either
+// is missing.
+func after(a string, b string) bool {
+	ta, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return false
+	}
+	tb, err := strconv.ParseFloat(b, 64)
+	if err != nil {
+		return false
+	}
+
+	return ta > tb
+}
+
+// MarkBlocker flags the item of the question asking for blockers.
+func (d *Data) MarkBlocker(index int) {
+	for i := range d.Items {
+		d.Items[i].Blocker = d.Items[i].Index == index
+	}
+}
+
+// Answered returns the items which have an answer.
+func (d Data) Answered() []Item {
+	var items []Item
+	for _, item := range d.Items {
+		if (item.Answer == "" && len(item.Files) == 0) || item.Answer == "none" {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// Day is the date of the standup, e.g. "Mon Sep 3".
+func (d Data) Day() string {
+	t, err := time.Parse("2006-01-02", d.Date)
+	if err != nil {
+		return d.Date
+	}
+
+	return t.Format("Mon Jan 2")
+}
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// isBlocks reports whether a template renders Block Kit JSON rather than
+// message text.
+func isBlocks(tmpl string) bool {
+	return strings.HasPrefix(strings.TrimSpace(tmpl), "[")
+}
+
+// Render executes a setting's template, or the default layout when it has
+// none. A template starting with "[" renders the JSON array of the blocks
+// of the message, others render its text.
+func Render(tmpl string, d Data) (slackapi.Message, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		return Default(d), nil
+	}
+
+	t, err := template.New("summary").Funcs(funcs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return slackapi.Message{}, err
+	}
+
+	out, err := execute(t, d)
+	if err != nil {
+		return slackapi.Message{}, err
+	}
+	out = strings.TrimSpace(out)
+
+	if !isBlocks(tmpl) {
+		if out == "" {
+			return slackapi.Message{}, errors.New("template renders an empty message")
+		}
+
+		return slackapi.Message{Text: out}, nil
+	}
+
+	var blocks []map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &blocks); err != nil {
+		return slackapi.Message{}, fmt.Errorf("template doesn't render a JSON array of blocks: %s", err)
+	}
+	if len(blocks) == 0 {
+		return slackapi.Message{}, errors.New("template renders no blocks")
+	}
+
+	// Text is the fallback of notifications
+	return slackapi.Message{Text: fallback(d), Blocks: json.RawMessage(out)}, nil
+}
+
+// execute runs a template within maxOutput, once it fits its budget.
+func execute(t *template.Template, d Data) (string, error) {
+	if err := checkBudget(t); err != nil {
+		return "", err
+	}
+
+	w := &limitedBuffer{max: maxOutput}
+	if err := t.Execute(w, d); err != nil {
+		return "", err
+	}
+
+	return w.String(), nil
+}
+
+// Validate renders a template with sample data having as many answers as
+// the setting has questions, so that a broken template is rejected when
+// the setting is saved.
+func Validate(tmpl string, questions int) error {
+	_, err := Render(tmpl, sample(questions))
+	return err
+}
+
+var sampleItems = []Item{
+	Item{Question: "What did you do yesterday?", Answer: "Reviewed \"the\" PR\nand more", Plain: "Reviewed \"the\" PR\nand more"},
+	Item{Question: "Anything blocking your progress?", Answer: "none", Plain: "none"},
+}
+
+func sample(questions int) Data {
+	d := Data{
+		User:   User{ID: "U0123ABCD", Name: "Jane Doe", Image: "https://example.com/jane.png"},
+		Date:   "2018-09-03",
+		Status: string(standup.StatusCompleted),
+	}
+
+	for i := 0; i < questions || i < len(sampleItems); i++ {
+		item := sampleItems[i%len(sampleItems)]
+		item.Index = i
+		d.Items = append(d.Items, item)
+	}
+
+	return d
+}
+
+// Default is the summary of settings without a template: a context with
+// the avatar and name of the member, then a section per answered question.
+func Default(d Data) slackapi.Message {
+	// Not a mention, which would notify the member
+	header := fmt.Sprintf("*%s* · %s", d.User.Name, d.Day())
+	if d.Late && !d.Threaded {
+		// Backfilled without the thread of its day to go to
+		header += " · _late entry_"
+	}
+
+	var context []interface{}
+	if d.User.Image != "" {
+		context = append(context, slackapi.Image(d.User.Image, d.User.Name))
+	}
+	context = append(context, slackapi.Markdown(header))
+
+	blocks := []interface{}{slackapi.Context(context...)}
+
+	for _, item := range d.Answered() {
+		text, truncated := truncate(item.Answer, maxAnswer)
+		if item.Edited {
+			text += " _(edited)_"
+		}
+		if len(item.Files) > 0 {
+			text = strings.TrimSpace(text + "\n" + files(item.Files))
+		}
+
+		section := slackapi.Section(fmt.Sprintf("*%s*\n%s", item.Question, text))
+		if truncated {
+			section.Accessory = slackapi.Button("Show more", ShowMoreActionID, ActionValue(d.User.ID, d.Date, item.Index))
+		}
+
+		blocks = append(blocks, slackapi.Divider(), section)
+	}
+
+	return blocksMessage(d, blocks...)
+}
+
+// escape escapes the control characters of Slack text.
+var escape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// files links the files of an answer.
+func files(files []File) string {
+	links := make([]string, len(files))
+	for i, f := range files {
+		if f.URL == "" {
+			links[i] = f.Name
+			continue
+		}
+		links[i] = fmt.Sprintf("<%s|%s>", f.URL, escape.Replace(f.Name))
+	}
+
+	return ":paperclip: " + strings.Join(links, ", ")
+}
+
+// Compact lists every answer of the member in a single section.
+func Compact(d Data) slackapi.Message {
+	lines := []string{fmt.Sprintf("*%s* · %s", d.User.Name, d.Day())}
+	for _, item := range d.Answered() {
+		text, _ := truncate(strings.Replace(item.Answer, "\n", " ", -1), maxAnswer)
+		if item.Edited {
+			text += " _(edited)_"
+		}
+		if len(item.Files) > 0 {
+			text = strings.TrimSpace(text + " " + files(item.Files))
+		}
+		lines = append(lines, fmt.Sprintf("• _%s_ %s", item.Question, text))
+	}
+
+	return blocksMessage(d, slackapi.Section(strings.Join(lines, "\n")))
+}
+
+// Blockers has the answer to the blockers question, and reports whether
+// there is one to post.
+func Blockers(d Data) (slackapi.Message, bool) {
+	for _, item := range d.Answered() {
+		if !item.Blocker {
+			continue
+		}
+
+		text, _ := truncate(item.Answer, maxAnswer)
+		section := slackapi.Section(fmt.Sprintf("*%s* · %s\n:warning: %s", d.User.Name, d.Day(), text))
+
+		return blocksMessage(d, section), true
+	}
+
+	text := fmt.Sprintf("*%s* · %s\nNo blockers", d.User.Name, d.Day())
+
+	return blocksMessage(d, slackapi.Section(text)), false
+}
+
+// Blocker returns the answered blocker item, nil if there is none or it
+// was answered with a "none"-like phrase.
+func (d Data) Blocker() *Item {
+	for _, item := range d.Answered() {
+		if item.Blocker && !IsNone(item.Answer) {
+			return &item
+		}
+	}
+
+	return nil
+}
+
+var nones = map[string]bool{
+	"":                 true,
+	"-":                true,
+	"n/a":              true,
+	"na":               true,
+	"no":               true,
+	"nope":             true,
+	"none":             true,
+	"nothing":          true,
+	"no blockers":      true,
+	"no blocker":       true,
+	"not blocked":      true,
+	"nothing blocking": true,
+	"all good":         true,
+}
+
+// IsNone reports whether an answer means there is nothing to report.
+func IsNone(answer string) bool {
+	a := strings.ToLower(strings.TrimSpace(answer))
+	a = strings.TrimRight(a, ".!:) ")
+
+	return nones[a]
+}
+
+// EscalationActionID is the action of the button resolving a blocker.
+const EscalationActionID = "blocker_resolved"
+
+// Escalation is the blocker of a member posted to the escalation channel,
+// with a button to resolve it until it has been.
+func Escalation(d Data, resolvedBy string, resolvedAt string) slackapi.Message {
+	text := fmt.Sprintf(":rotating_light: *%s* is blocked · %s\n", d.User.Name, d.Day())
+	index := 0
+	if blocker := d.Blocker(); blocker != nil {
+		answer, _ := truncate(blocker.Answer, 2000)
+		text += "> " + strings.Replace(answer, "\n", "\n> ", -1)
+		index = blocker.Index
+	} else {
+		text += "_The blocker has been removed from the stand-up._"
+	}
+
+	section := slackapi.Section(text)
+
+	if resolvedBy == "" {
+		section.Accessory = slackapi.Button("Resolved", EscalationActionID, ActionValue(d.User.ID, d.Date, index))
+		return blocksMessage(d, section)
+	}
+
+	resolved := fmt.Sprintf(":white_check_mark: Resolved by <@%s>", resolvedBy)
+	if t, err := time.Parse(time.RFC3339, resolvedAt); err == nil {
+		resolved += fmt.Sprintf(" <!date^%d^{date_short_pretty} at {time}|%s>", t.Unix(), resolvedAt)
+	}
+
+	return blocksMessage(d, section, slackapi.Context(slackapi.Markdown(resolved)))
+}
+
+func blocksMessage(d Data, blocks ...interface{}) slackapi.Message {
+	b, err := json.Marshal(blocks)
+	if err != nil {
+		// Only built from strings, can't happen
+		panic(err)
+	}
+
+	return slackapi.Message{Text: fallback(d), Blocks: json.RawMessage(b)}
+}
+
+// maxAnswer is the length over which an answer is cut in the default
+// summary, with a button showing all of it.
+const maxAnswer = 500
+
+// ShowMoreActionID is the action of the button showing a whole answer.
+const ShowMoreActionID = "summary_show_more"
+
+// ActionValue identifies an answer in the value of a button.
+func ActionValue(userID string, date string, index int) string {
+	return fmt.Sprintf("%s/%s/%d", userID, date, index)
+}
+
+// ParseActionValue is the reverse of ActionValue.
+func ParseActionValue(v string) (userID string, date string, index int, err error) {
+	parts := strings.Split(v, "/")
+	if len(parts) != 3 {
+		return "", "", 0, fmt.Errorf("invalid value: %q", v)
+	}
+
+	index, err = strconv.Atoi(parts[2])
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	return parts[0], parts[1], index, nil
+}
+
+// truncate cuts s to max runes, at a space when there is one nearby.
+func truncate(s string, max int) (string, bool) {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s, false
+	}
+
+	cut := string(runes[:max])
+	if i := strings.LastIndex(cut, "<"); i > strings.LastIndex(cut, ">") {
+		// Not in the middle of a link or a mention
+		cut = cut[:i]
+	}
+	if i := strings.LastIndexAny(cut, " \n"); i > max/2 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimSpace(cut) + "…", true
+}
+
+func fallback(d Data) string {
+	return fmt.Sprintf("Stand-up of %s for %s", d.User.Name, d.Day())
+}
+
+// limitedBuffer fails writes past max bytes.
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, fmt.Errorf("template renders more than %d bytes", b.max)
+	}
+
+	return b.Buffer.Write(p)
+}
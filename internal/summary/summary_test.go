@@ -0,0 +1,209 @@
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+)
+
+func TestRenderText(t *testing.T) {
+	msg, err := Render(`*{{.User.Name}}* on {{.Day}}{{range .Answered}}
+{{.Question}}: {{.Answer}}{{end}}`, sample(2))
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	want := "*Jane Doe* on Mon Sep 3\nWhat did you do yesterday?: Reviewed \"the\" PR\nand more"
+	if msg.Text != want || len(msg.Blocks) != 0 {
+		t.Fatalf("Want %q, got %+v", want, msg)
+	}
+}
+
+func TestRenderBlocks(t *testing.T) {
+	msg, err := Render(`[{{range $i, $item := .Answered}}{{if $i}},{{end}}
+{"type":"section","text":{"type":"mrkdwn","text":{{json $item.Answer}}}}{{end}}]`, sample(2))
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if !strings.Contains(string(msg.Blocks), `"Reviewed \"the\" PR\nand more"`) || msg.Text == "" {
+		t.Fatalf("Unexpected message: %+v", msg)
+	}
+}
+
+func TestValidateRejectsBrokenTemplates(t *testing.T) {
+	templates := []string{
+		`{{.User.Name`,
+		`{{.Nope}}`,
+		`[{"type":"section","text":{{.User.Name}}}]`,
+		`{{define "loop"}}{{template "loop"}}{{end}}{{template "loop"}}`,
+		`{{range .Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{range $.Items}}{{$.User.Image}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}{{end}}`,
+		`{{if false}}x{{end}}`,
+	}
+
+	for _, tmpl := range templates {
+		if err := Validate(tmpl, 2); err == nil {
+			t.Fatalf("Want an error for %q", tmpl)
+		}
+	}
+
+	if err := Validate("", 2); err != nil {
+		t.Fatalf("Want the default to be valid, got %q", err)
+	}
+}
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestDefaultGolden(t *testing.T) {
+	long := sample(2)
+	long.Items = []Item{
+		Item{Index: 0, Question: "What did you do yesterday?", Answer: strings.Repeat("Fixed a flaky test. ", 40)},
+		Item{Index: 1, Question: "What will you do today?", Answer: "Release"},
+	}
+
+	late := sample(2)
+	late.Late = true
+
+	edited := sample(2)
+	edited.Items[0].Edited = true
+
+	files := sample(2)
+	files.Items = []Item{
+		Item{Index: 0, Question: "What did you do yesterday?", Answer: "Fixed the layout", Files: []File{File{Name: "before & after.png", URL: "https://example.slack.com/files/U1/F1/before.png"}}},
+		Item{Index: 1, Question: "What will you do today?", Files: []File{File{Name: "plan.txt", URL: "https://example.slack.com/files/U1/F2/plan.txt"}}},
+	}
+
+	blockers := sample(2)
+	blockers.MarkBlocker(0)
+
+	tests := map[string]func() slackapi.Message{
+		"default": func() slackapi.Message { return Default(sample(2)) },
+		"long":    func() slackapi.Message { return Default(long) },
+		"late":    func() slackapi.Message { return Default(late) },
+		"compact": func() slackapi.Message { return Compact(long) },
+		"edited":  func() slackapi.Message { return Default(edited) },
+		"files":   func() slackapi.Message { return Default(files) },
+		"blockers": func() slackapi.Message {
+			msg, _ := Blockers(blockers)
+			return msg
+		},
+		"escalation": func() slackapi.Message { return Escalation(blockers, "", "") },
+		"resolved":   func() slackapi.Message { return Escalation(blockers, "U2", "2018-09-03T10:00:00Z") },
+	}
+
+	for name, render := range tests {
+		msg := render()
+
+		var got bytes.Buffer
+		if err := json.Indent(&got, msg.Blocks, "", "  "); err != nil {
+			t.Fatalf("%s: %q", name, err)
+		}
+		got.WriteString("\n")
+
+		path := filepath.Join("testdata", name+".golden")
+		if *update {
+			if err := ioutil.WriteFile(path, got.Bytes(), 0644); err != nil {
+				t.Fatalf("%q", err)
+			}
+		}
+
+		want, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%q", err)
+		}
+
+		if !bytes.Equal(got.Bytes(), want) {
+			t.Fatalf("%s: want\n%s\ngot\n%s", name, want, got.Bytes())
+		}
+	}
+}
+
+func TestActionValue(t *testing.T) {
+	userID, date, index, err := ParseActionValue(ActionValue("U1", "2018-09-03", 2))
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if userID != "U1" || date != "2018-09-03" || index != 2 {
+		t.Fatalf("Unexpected %s %s %d", userID, date, index)
+	}
+}
+
+func TestBlockersWithoutBlocker(t *testing.T) {
+	d := sample(2)
+	d.MarkBlocker(1)
+
+	if _, ok := Blockers(d); ok {
+		t.Fatal("Want nothing to post when the blocker is \"none\"")
+	}
+}
+
+func TestIsNone(t *testing.T) {
+	for answer, want := range map[string]bool{"none": true, " None ": true, "n/a": true, "Nothing.": true, "a flaky deploy": false} {
+		if got := IsNone(answer); got != want {
+			t.Fatalf("%q: want %t, got %t", answer, want, got)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	if !after("1535965320.000000", "1535965260.000100") || after("1535965260.000100", "1535965320.000000") || after("1535965320.000000", "") {
+		t.Fatal("Unexpected order of timestamps")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	d := sample(2)
+	d.Items = []Item{Item{Index: 0, Question: "What did you do yesterday?", Answer: "Paired with <@U2> on <https://example.com/pr/1|the PR>"}}
+
+	if got := d.Mentions(); len(got) != 1 || got[0] != "U2" {
+		t.Fatalf("Want U2, got %v", got)
+	}
+
+	d.Resolve(map[string]string{"U2": "John Roe"})
+
+	item := d.Items[0]
+	if item.Answer != "Paired with @John Roe on <https://example.com/pr/1|the PR>" {
+		t.Fatalf("Unexpected answer %q", item.Answer)
+	}
+	if item.Plain != "Paired with @John Roe on the PR (https://example.com/pr/1)" {
+		t.Fatalf("Unexpected plain answer %q", item.Plain)
+	}
+}
+
+func TestTruncateKeepsLinks(t *testing.T) {
+	text := strings.Repeat("a", 480) + " <https://example.com/a/long/link|a link>"
+
+	got, truncated := truncate(text, maxAnswer)
+	if !truncated || strings.Contains(got, "<") {
+		t.Fatalf("Want the link left out, got %q", got)
+	}
+}
+
+func TestValidateChecksBudget(t *testing.T) {
+	templates := map[string]string{
+		"ranges nested 3 times": `{{range .Items}}{{range $.Items}}{{range $.Items}}{{end}}{{end}}{{end}}{{.User.Name}}`,
+		"ranges in a called template": `{{define "answers"}}{{range $.Items}}{{range $.Items}}{{end}}{{end}}{{end}}` +
+			`{{range .Items}}{{template "answers" $}}{{end}}{{.User.Name}}`,
+		"range over a number":  `{{range 1000000000}}{{end}}{{.User.Name}}`,
+		"range over a call":    `{{range (join .Items ",")}}{{end}}{{.User.Name}}`,
+		"too many nodes":       strings.Repeat("{{.User.Name}}", maxNodes),
+		"calls itself in else": `{{define "a"}}{{if false}}{{else}}{{template "a"}}{{end}}{{end}}{{template "a"}}`,
+	}
+
+	for name, tmpl := range templates {
+		if err := Validate(tmpl, 10); err == nil {
+			t.Fatalf("Want an error for a template with %s", name)
+		}
+	}
+
+	if err := Validate(`{{range .Items}}{{range .Files}}{{.Name}}{{end}}{{.Question}}{{end}}`, 10); err != nil {
+		t.Fatalf("Want ranges nested twice to be valid, got %q", err)
+	}
+}
@@ -0,0 +1,93 @@
+package thread
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/guregu/dynamo"
+)
+
+var threadsTable = os.Getenv("THREADS_TABLE")
+
+// Thread is the parent message of the summaries posted to a target channel
+// on a day, when the setting has threaded summaries.
+type Thread struct {
+	TargetChannelID string   `dynamo:"target_channel_id"`
+	Date            string   `dynamo:"date"`
+	Timestamp       string   `dynamo:"ts"`
+	UserIDs         []string `dynamo:"user_ids,set"`
+	Done            []string `dynamo:"done,set"`
+}
+
+func Get(db *dynamo.DB, targetChannelID string, date string) (*Thread, error) {
+	table := db.Table(threadsTable)
+
+	var t Thread
+	if err := table.Get("target_channel_id", targetChannelID).Range("date", dynamo.Equal, date).Consistent(true).One(&t); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func Create(db *dynamo.DB, t Thread) error {
+	table := db.Table(threadsTable)
+
+	if err := table.Put(t).Run(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetTimestamp records the parent message once it is posted.
+func SetTimestamp(db *dynamo.DB, targetChannelID string, date string, ts string) (*Thread, error) {
+	return update(db.Table(threadsTable).Update("target_channel_id", targetChannelID).
+		Range("date", date).
+		Set("ts", ts))
+}
+
+// Join adds a member to the thread, e.g. for a backfilled standup.
+func Join(db *dynamo.DB, targetChannelID string, date string, userID string) (*Thread, error) {
+	return update(db.Table(threadsTable).Update("target_channel_id", targetChannelID).
+		Range("date", date).
+		AddStringsToSet("user_ids", userID))
+}
+
+// MarkDone records that a member has finished. It is safe to call again
+// when the summary of the member is edited.
+func MarkDone(db *dynamo.DB, targetChannelID string, date string, userID string) (*Thread, error) {
+	return update(db.Table(threadsTable).Update("target_channel_id", targetChannelID).
+		Range("date", date).
+		AddStringsToSet("user_ids", userID).
+		AddStringsToSet("done", userID))
+}
+
+// Unmark counts a member as not finished anymore, e.g. once the summary of
+// the member was retracted.
+func Unmark(db *dynamo.DB, targetChannelID string, date string, userID string) (*Thread, error) {
+	return update(db.Table(threadsTable).Update("target_channel_id", targetChannelID).
+		Range("date", date).
+		DeleteStringsFromSet("done", userID))
+}
+
+func update(u *dynamo.Update) (*Thread, error) {
+	var t Thread
+	if err := u.If("attribute_exists('target_channel_id')").Value(&t); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// Text is the text of the parent message, e.g.
+// "Standup for Tue Oct 20 — 3/8 done".
+func (t *Thread) Text() string {
+	day := t.Date
+	if d, err := time.Parse("2006-01-02", t.Date); err == nil {
+		day = d.Format("Mon Jan 2")
+	}
+
+	return fmt.Sprintf("Standup for %s — %d/%d done", day, len(t.Done), len(t.UserIDs))
+}
@@ -0,0 +1,16 @@
+package thread
+
+import "testing"
+
+func TestText(t *testing.T) {
+	th := &Thread{
+		Date:    "2018-10-20",
+		UserIDs: []string{"U1", "U2", "U3"},
+		Done:    []string{"U2"},
+	}
+
+	want := "Standup for Sat Oct 20 — 1/3 done"
+	if got := th.Text(); got != want {
+		t.Fatalf("Want %q, got %q", want, got)
+	}
+}
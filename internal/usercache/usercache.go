@@ -0,0 +1,180 @@
+package usercache
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+)
+
+var usersTable = os.Getenv("USERS_TABLE")
+
+// Entries are kept in memory for a warm Lambda container and in the table
+// until they are refreshed by a user_change event or become too old.
+var (
+	memoryTTL = 10 * time.Minute
+	storeTTL  = 24 * time.Hour
+)
+
+// botKey prefixes the rows holding the identity of the bot of each team,
+// it can't clash with Slack user IDs.
+const botKey = "@bot"
+
+var now = time.Now
+
+type User struct {
+	UserID    string `dynamo:"user_id"`
+	TZ        string `dynamo:"tz"`
+	RealName  string `dynamo:"real_name"`
+	Image32   string `dynamo:"image_32"`
+	UpdatedAt int64  `dynamo:"updated_at"`
+	ExpiresAt int64  `dynamo:"expires_at"`
+}
+
+type identity struct {
+	UserID    string `dynamo:"user_id"`
+	BotUserID string `dynamo:"bot_user_id"`
+	TeamID    string `dynamo:"team_id"`
+}
+
+type entry struct {
+	user    User
+	expires time.Time
+}
+
+var (
+	mu         sync.Mutex
+	users      = map[string]entry{}
+	identities = map[string]slackapi.Identity{}
+)
+
+// Get returns the cached user, falling back to users.info on a miss.
+// A broken cache only costs a Slack call, so its errors are just logged.
+func Get(ctx context.Context, db *dynamo.DB, cl slackapi.Client, userID string) (*User, error) {
+	if u, ok := fromMemory(userID); ok {
+		return &u, nil
+	}
+
+	table := db.Table(usersTable)
+
+	var u User
+	err := table.Get("user_id", userID).One(&u)
+	switch {
+	case err == nil && now().Sub(time.Unix(u.UpdatedAt, 0)) < storeTTL:
+		remember(u)
+		return &u, nil
+	case err != nil && err != dynamo.ErrNotFound:
+		log.Printf("failed to read user cache of %s: %s", userID, err)
+	}
+
+	info, err := cl.GetUserInfo(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	u = fromSlack(*info)
+	if err := table.Put(u).Run(); err != nil {
+		log.Printf("failed to write user cache of %s: %s", userID, err)
+	}
+	remember(u)
+
+	return &u, nil
+}
+
+// Refresh replaces the cached user, e.g. from a user_change event.
+func Refresh(db *dynamo.DB, info slackapi.User) error {
+	table := db.Table(usersTable)
+
+	u := fromSlack(info)
+	if err := table.Put(u).Run(); err != nil {
+		return err
+	}
+	remember(u)
+
+	return nil
+}
+
+// GetIdentity returns the auth.test result of the bot token of a team,
+// which never changes for a given installation.
+func GetIdentity(ctx context.Context, db *dynamo.DB, cl slackapi.Client, teamID string) (*slackapi.Identity, error) {
+	key := botKey
+	if teamID != "" {
+		key += ":" + teamID
+	}
+
+	mu.Lock()
+	cached, ok := identities[key]
+	mu.Unlock()
+	if ok {
+		return &cached, nil
+	}
+
+	table := db.Table(usersTable)
+
+	var stored identity
+	err := table.Get("user_id", key).One(&stored)
+	if err == nil {
+		id := slackapi.Identity{UserID: stored.BotUserID, TeamID: stored.TeamID}
+		rememberIdentity(key, id)
+		return &id, nil
+	}
+	if err != dynamo.ErrNotFound {
+		log.Printf("failed to read bot identity cache: %s", err)
+	}
+
+	id, err := cl.AuthTest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stored = identity{UserID: key, BotUserID: id.UserID, TeamID: id.TeamID}
+	if err := table.Put(stored).Run(); err != nil {
+		log.Printf("failed to write bot identity cache: %s", err)
+	}
+	rememberIdentity(key, *id)
+
+	return id, nil
+}
+
+func fromSlack(info slackapi.User) User {
+	t := now()
+
+	return User{
+		UserID:    info.ID,
+		TZ:        info.TZ,
+		RealName:  info.Profile.RealName,
+		Image32:   info.Profile.Image32,
+		UpdatedAt: t.Unix(),
+		ExpiresAt: t.Add(storeTTL).Unix(),
+	}
+}
+
+func fromMemory(userID string) (User, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	e, ok := users[userID]
+	if !ok || now().After(e.expires) {
+		return User{}, false
+	}
+
+	return e.user, true
+}
+
+func remember(u User) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	users[u.UserID] = entry{user: u, expires: now().Add(memoryTTL)}
+}
+
+func rememberIdentity(key string, id slackapi.Identity) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	identities[key] = id
+}
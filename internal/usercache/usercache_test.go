@@ -0,0 +1,131 @@
+package usercache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+)
+
+type mockedDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	Items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func (m *mockedDynamo) GetItemWithContext(context aws.Context, input *dynamodb.GetItemInput, options ...request.Option) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.Items[*input.Key["user_id"].S]}, nil
+}
+
+func (m *mockedDynamo) PutItemWithContext(context aws.Context, input *dynamodb.PutItemInput, options ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.Items[*input.Item["user_id"].S] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+type countingClient struct {
+	*slackapi.Fake
+	Calls int
+}
+
+func (c *countingClient) GetUserInfo(ctx context.Context, userID string) (*slackapi.User, error) {
+	c.Calls++
+	return c.Fake.GetUserInfo(ctx, userID)
+}
+
+func (c *countingClient) AuthTest(ctx context.Context) (*slackapi.Identity, error) {
+	c.Calls++
+	return c.Fake.AuthTest(ctx)
+}
+
+func reset() {
+	users = map[string]entry{}
+	identities = map[string]slackapi.Identity{}
+}
+
+func TestGetSuccess(t *testing.T) {
+	reset()
+
+	cl := &countingClient{Fake: &slackapi.Fake{
+		Users: map[string]slackapi.User{
+			"U1": slackapi.User{ID: "U1", TZ: "Asia/Tokyo", Profile: slackapi.Profile{RealName: "Alice"}},
+		},
+	}}
+	mockedClient := &mockedDynamo{Items: map[string]map[string]*dynamodb.AttributeValue{}}
+	db := dynamo.NewFromIface(mockedClient)
+
+	for i := 0; i < 2; i++ {
+		u, err := Get(context.Background(), db, cl, "U1")
+		if err != nil {
+			t.Fatalf("%q", err)
+		}
+
+		if u.TZ != "Asia/Tokyo" || u.RealName != "Alice" {
+			t.Fatalf("Unexpected user: %+v", u)
+		}
+	}
+
+	if cl.Calls != 1 {
+		t.Fatalf("Want 1 call to Slack, got %d", cl.Calls)
+	}
+
+	// A cold container is served from the table
+	reset()
+	if _, err := Get(context.Background(), db, cl, "U1"); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if cl.Calls != 1 {
+		t.Fatalf("Want 1 call to Slack, got %d", cl.Calls)
+	}
+}
+
+func TestRefreshSuccess(t *testing.T) {
+	reset()
+
+	cl := &countingClient{Fake: &slackapi.Fake{}}
+	mockedClient := &mockedDynamo{Items: map[string]map[string]*dynamodb.AttributeValue{}}
+	db := dynamo.NewFromIface(mockedClient)
+
+	err := Refresh(db, slackapi.User{ID: "U1", TZ: "Europe/Paris"})
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	u, err := Get(context.Background(), db, cl, "U1")
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if u.TZ != "Europe/Paris" || cl.Calls != 0 {
+		t.Fatalf("Unexpected user: %+v, calls: %d", u, cl.Calls)
+	}
+}
+
+func TestGetIdentitySuccess(t *testing.T) {
+	reset()
+
+	cl := &countingClient{Fake: &slackapi.Fake{Identity: slackapi.Identity{UserID: "UBOT", TeamID: "T1"}}}
+	mockedClient := &mockedDynamo{Items: map[string]map[string]*dynamodb.AttributeValue{}}
+	db := dynamo.NewFromIface(mockedClient)
+
+	for i := 0; i < 2; i++ {
+		reset()
+
+		id, err := GetIdentity(context.Background(), db, cl, "T1")
+		if err != nil {
+			t.Fatalf("%q", err)
+		}
+
+		if id.UserID != "UBOT" {
+			t.Fatalf("Want %q, got %q", "UBOT", id.UserID)
+		}
+	}
+
+	if cl.Calls != 1 {
+		t.Fatalf("Want 1 call to Slack, got %d", cl.Calls)
+	}
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"strings"
+	"sync"
+)
+
+// Errors collects the errors of independent operations so that one failure
+// doesn't hide the others.
+type Errors []error
+
+func (es Errors) Error() string {
+	msgs := make([]string, len(es))
+	for i, err := range es {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Err returns nil when nothing failed, so that callers can return it directly.
+func (es Errors) Err() error {
+	if len(es) == 0 {
+		return nil
+	}
+	return es
+}
+
+// Each calls f for every element of vs with at most limit calls running at
+// the same time, and returns the errors of all failed calls.
+func Each(limit int, vs []string, f func(string) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs Errors
+	)
+
+	sem := make(chan struct{}, limit)
+	for _, v := range vs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(v string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := f(v); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(v)
+	}
+	wg.Wait()
+
+	return errs.Err()
+}
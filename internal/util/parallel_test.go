@@ -0,0 +1,74 @@
+package util
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestEachSuccess(t *testing.T) {
+	in := []string{"1", "2", "3", "4", "5"}
+
+	var (
+		mu          sync.Mutex
+		running     int
+		maxRunning  int
+		got         []string
+		limit       = 2
+		release     = make(chan struct{})
+		releaseOnce sync.Once
+	)
+
+	err := Each(limit, in, func(v string) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		got = append(got, v)
+		if running == limit {
+			releaseOnce.Do(func() { close(release) })
+		}
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	sort.Strings(got)
+	if len(got) != len(in) {
+		t.Fatalf("Want %q, got %q", in, got)
+	}
+
+	if maxRunning > limit {
+		t.Fatalf("Want at most %d running, got %d", limit, maxRunning)
+	}
+}
+
+func TestEachCollectsErrors(t *testing.T) {
+	in := []string{"ok", "ng1", "ok", "ng2"}
+
+	err := Each(3, in, func(v string) error {
+		if v == "ok" {
+			return nil
+		}
+		return errors.New(v)
+	})
+
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("Want Errors, got %T", err)
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("Want 2 errors, got %q", errs)
+	}
+}
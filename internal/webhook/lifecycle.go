@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/schedule"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/usercache"
+	"github.com/tsub/serverless-daily-standup-bot/internal/util"
+)
+
+// handledEvents are the event types enqueued by Accept, the others are
+// acknowledged and dropped.
+var handledEvents = map[string]bool{
+	"message":               true,
+	"user_change":           true,
+	"app_uninstalled":       true,
+	"tokens_revoked":        true,
+	"channel_archive":       true,
+	"channel_unarchive":     true,
+	"channel_deleted":       true,
+	"channel_rename":        true,
+	"group_archive":         true,
+	"group_unarchive":       true,
+	"group_deleted":         true,
+	"group_rename":          true,
+	"member_joined_channel": true,
+	"member_left_channel":   true,
+}
+
+// channel is the "channel" of an event, which is an ID for most events and
+// an object for channel_rename.
+type channel struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+func (c *channel) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &c.ID); err == nil {
+		return nil
+	}
+
+	type object channel
+	return json.Unmarshal(b, (*object)(c))
+}
+
+// tokens lists the user IDs whose tokens were revoked.
+type tokens struct {
+	OAuth []string `json:"oauth,omitempty"`
+	Bot   []string `json:"bot,omitempty"`
+}
+
+// uninstall disables every setting of the team and forgets its tokens. A
+// reinstall enables the settings again.
+func uninstall(ctx context.Context, db *dynamo.DB, teamID string) error {
+	if err := disableTeam(ctx, db, teamID); err != nil {
+		return err
+	}
+
+	if err := installation.Delete(db, teamID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// revokeTokens disables the team once the bot can't act anymore. A revoked
+// user token only drops back to the bot token.
+func revokeTokens(ctx context.Context, db *dynamo.DB, teamID string, t tokens) error {
+	if len(t.Bot) > 0 {
+		return uninstall(ctx, db, teamID)
+	}
+
+	i, err := installation.Get(db, teamID)
+	if err == dynamo.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range t.OAuth {
+		if userID == i.UserID {
+			revoked := *i
+			revoked.UserToken = ""
+			revoked.UserScope = ""
+
+			return installation.Save(db, revoked)
+		}
+	}
+
+	return nil
+}
+
+func disableTeam(ctx context.Context, db *dynamo.DB, teamID string) error {
+	settings, err := setting.ByTeam(db, teamID)
+	if err != nil {
+		return err
+	}
+
+	var errs util.Errors
+	for _, s := range settings {
+		if err := setting.SetDisabled(db, s.TargetChannelID, true); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := schedule.Current().Disable(ctx, teamID, s.TargetChannelID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	log.Printf("disabled %d settings of team %s", len(settings), teamID)
+
+	return errs.Err()
+}
+
+// pause stops stand-ups of an archived or deleted channel, and resumes them
+// when the channel is unarchived.
+func pause(db *dynamo.DB, channelID string, paused bool) error {
+	_, err := setting.Get(db, channelID)
+	if err == dynamo.ErrNotFound {
+		// Not a target channel
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return setting.SetPaused(db, channelID, paused)
+}
+
+func memberJoined(ctx context.Context, db *dynamo.DB, botcl slackapi.Client, teamID string, channelID string, userID string) error {
+	s, err := channelDriven(db, channelID)
+	if s == nil || err != nil {
+		return err
+	}
+
+	identity, err := usercache.GetIdentity(ctx, db, botcl, teamID)
+	if err != nil {
+		return err
+	}
+
+	u, err := botcl.GetUserInfo(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Bots, including ourselves, don't answer stand-ups
+	if userID == identity.UserID || u.IsBot {
+		return nil
+	}
+
+	return setting.AddUser(db, channelID, userID)
+}
+
+func memberLeft(db *dynamo.DB, channelID string, userID string) error {
+	s, err := channelDriven(db, channelID)
+	if s == nil || err != nil {
+		return err
+	}
+
+	return setting.RemoveUser(db, channelID, userID)
+}
+
+// channelDriven returns the setting of a channel if its members are the
+// members of the channel, or nil.
+func channelDriven(db *dynamo.DB, channelID string) (*setting.Setting, error) {
+	s, err := setting.Get(db, channelID)
+	if err == dynamo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.MembersFromChannel {
+		return nil, nil
+	}
+
+	return s, nil
+}
@@ -0,0 +1,348 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/schedule"
+	"github.com/tsub/serverless-daily-standup-bot/internal/setting"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+)
+
+// lifecycleDynamo serves settings and an installation, and records what is
+// written.
+type lifecycleDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	Settings     []setting.Setting
+	Installation *installation.Installation
+
+	Updates []*dynamodb.UpdateItemInput
+	Puts    []*dynamodb.PutItemInput
+	Deletes []*dynamodb.DeleteItemInput
+}
+
+func (m *lifecycleDynamo) ScanWithContext(context aws.Context, input *dynamodb.ScanInput, options ...request.Option) (*dynamodb.ScanOutput, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, s := range m.Settings {
+		item, err := dynamo.MarshalItem(s)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return &dynamodb.ScanOutput{Items: items, Count: aws.Int64(int64(len(items)))}, nil
+}
+
+func (m *lifecycleDynamo) GetItemWithContext(context aws.Context, input *dynamodb.GetItemInput, options ...request.Option) (*dynamodb.GetItemOutput, error) {
+	var found interface{}
+	if key, ok := input.Key["target_channel_id"]; ok {
+		for _, s := range m.Settings {
+			if s.TargetChannelID == aws.StringValue(key.S) {
+				found = s
+			}
+		}
+	}
+	if _, ok := input.Key["team_id"]; ok && m.Installation != nil {
+		found = *m.Installation
+	}
+	if found == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+
+	item, err := dynamo.MarshalItem(found)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (m *lifecycleDynamo) UpdateItemWithContext(context aws.Context, input *dynamodb.UpdateItemInput, options ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	m.Updates = append(m.Updates, input)
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (m *lifecycleDynamo) PutItemWithContext(context aws.Context, input *dynamodb.PutItemInput, options ...request.Option) (*dynamodb.PutItemOutput, error) {
+	m.Puts = append(m.Puts, input)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *lifecycleDynamo) DeleteItemWithContext(context aws.Context, input *dynamodb.DeleteItemInput, options ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	m.Deletes = append(m.Deletes, input)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// updated lists the settings updated with an expression containing op,
+// e.g. "disabled" or "ADD".
+func (m *lifecycleDynamo) updated(op string) []string {
+	var channelIDs []string
+	for _, input := range m.Updates {
+		expr := aws.StringValue(input.UpdateExpression)
+		for _, name := range input.ExpressionAttributeNames {
+			expr += " " + aws.StringValue(name)
+		}
+		if strings.Contains(expr, op) {
+			channelIDs = append(channelIDs, aws.StringValue(input.Key["target_channel_id"].S))
+		}
+	}
+
+	return channelIDs
+}
+
+// recordingScheduler records the schedules disabled, and fails for the
+// channels in fail.
+type recordingScheduler struct {
+	Disabled []string
+	fail     map[string]bool
+}
+
+func (s *recordingScheduler) Get(ctx context.Context, teamID string, targetChannelID string) (string, error) {
+	return "", nil
+}
+
+func (s *recordingScheduler) Put(ctx context.Context, teamID string, targetChannelID string, expr string) error {
+	return nil
+}
+
+func (s *recordingScheduler) Disable(ctx context.Context, teamID string, targetChannelID string) error {
+	if s.fail[targetChannelID] {
+		return errors.New("schedule: failed")
+	}
+
+	s.Disabled = append(s.Disabled, targetChannelID)
+	return nil
+}
+
+func teamSettings(teamID string) []setting.Setting {
+	return []setting.Setting{
+		setting.Setting{TeamID: teamID, TargetChannelID: "C1", ScheduleExpression: "cron(0 1 ? * MON-FRI *)"},
+		setting.Setting{TeamID: teamID, TargetChannelID: "C2", ScheduleExpression: "cron(0 2 ? * MON-FRI *)"},
+	}
+}
+
+func TestAcceptEnqueuesLifecycleEvents(t *testing.T) {
+	bodies := []string{
+		`{"type":"event_callback","team_id":"T1","event":{"type":"app_uninstalled"}}`,
+		`{"type":"event_callback","team_id":"T1","event":{"type":"tokens_revoked","tokens":{"bot":["U1"]}}}`,
+		`{"type":"event_callback","team_id":"T1","event":{"type":"channel_archive","channel":"C1","user":"U1"}}`,
+		`{"type":"event_callback","team_id":"T1","event":{"type":"channel_deleted","channel":"C1"}}`,
+		`{"type":"event_callback","team_id":"T1","event":{"type":"channel_rename","channel":{"id":"C1","name":"standup","created":1360782804}}}`,
+		`{"type":"event_callback","team_id":"T1","event":{"type":"member_joined_channel","user":"U1","channel":"C1","channel_type":"C"}}`,
+		`{"type":"event_callback","team_id":"T1","event":{"type":"member_left_channel","user":"U1","channel":"C1","channel_type":"C"}}`,
+	}
+
+	for _, body := range bodies {
+		q := &recordingQueue{}
+
+		status, _, err := Accept(context.Background(), q, []byte(body))
+		if err != nil {
+			t.Fatalf("%q", err)
+		}
+
+		if status != 200 || len(q.Jobs) != 1 {
+			t.Fatalf("Unexpected status %d, jobs %+v for %s", status, q.Jobs, body)
+		}
+
+		var e envelope
+		if err := json.Unmarshal(q.Jobs[0].Payload, &e); err != nil {
+			t.Fatalf("%q", err)
+		}
+		if e.Event.Type != "app_uninstalled" && e.Event.Type != "tokens_revoked" && e.Event.Channel.ID != "C1" {
+			t.Fatalf("Want channel C1, got %+v", e.Event.Channel)
+		}
+	}
+}
+
+func TestChannelUnmarshalObject(t *testing.T) {
+	var c channel
+	if err := json.Unmarshal([]byte(`{"id":"C1","name":"standup"}`), &c); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if c.ID != "C1" || c.Name != "standup" {
+		t.Fatalf("Unexpected channel: %+v", c)
+	}
+}
+
+func TestUninstall(t *testing.T) {
+	sched := &recordingScheduler{}
+	schedule.Use(sched)
+	defer schedule.Use(nil)
+
+	mocked := &lifecycleDynamo{Settings: teamSettings("T10")}
+	db := dynamo.NewFromIface(mocked)
+
+	if err := uninstall(context.Background(), db, "T10"); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if got := mocked.updated("disabled"); !reflect.DeepEqual(got, []string{"C1", "C2"}) {
+		t.Fatalf("Want C1 and C2 to be disabled, got %v", got)
+	}
+	if !reflect.DeepEqual(sched.Disabled, []string{"C1", "C2"}) {
+		t.Fatalf("Want the schedules of C1 and C2 to be disabled, got %v", sched.Disabled)
+	}
+	if len(mocked.Deletes) != 1 || aws.StringValue(mocked.Deletes[0].Key["team_id"].S) != "T10" {
+		t.Fatalf("Want the installation to be deleted, got %+v", mocked.Deletes)
+	}
+}
+
+func TestDisableTeamContinuesAfterError(t *testing.T) {
+	sched := &recordingScheduler{fail: map[string]bool{"C1": true}}
+	schedule.Use(sched)
+	defer schedule.Use(nil)
+
+	mocked := &lifecycleDynamo{Settings: teamSettings("T11")}
+	db := dynamo.NewFromIface(mocked)
+
+	if err := disableTeam(context.Background(), db, "T11"); err == nil {
+		t.Fatal("Want the error of C1")
+	}
+
+	if got := mocked.updated("disabled"); !reflect.DeepEqual(got, []string{"C1", "C2"}) {
+		t.Fatalf("Want C1 and C2 to be disabled, got %v", got)
+	}
+	if !reflect.DeepEqual(sched.Disabled, []string{"C2"}) {
+		t.Fatalf("Want the schedule of C2 to be disabled, got %v", sched.Disabled)
+	}
+}
+
+func TestRevokeBotToken(t *testing.T) {
+	sched := &recordingScheduler{}
+	schedule.Use(sched)
+	defer schedule.Use(nil)
+
+	mocked := &lifecycleDynamo{Settings: teamSettings("T12")}
+	db := dynamo.NewFromIface(mocked)
+
+	if err := revokeTokens(context.Background(), db, "T12", tokens{Bot: []string{"UBOT"}}); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(mocked.Deletes) != 1 || len(sched.Disabled) != 2 {
+		t.Fatalf("Want the team to be uninstalled, got deletes %+v, schedules %v", mocked.Deletes, sched.Disabled)
+	}
+}
+
+func TestRevokeUserToken(t *testing.T) {
+	mocked := &lifecycleDynamo{Installation: &installation.Installation{
+		TeamID:    "T13",
+		BotToken:  "xoxb-1",
+		UserID:    "U1",
+		UserToken: "xoxp-1",
+		UserScope: "users:read",
+	}}
+	db := dynamo.NewFromIface(mocked)
+
+	// Another user's token leaves the installation as it is
+	if err := revokeTokens(context.Background(), db, "T13", tokens{OAuth: []string{"U2"}}); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if len(mocked.Puts) != 0 {
+		t.Fatalf("Want no change, got %+v", mocked.Puts)
+	}
+
+	if err := revokeTokens(context.Background(), db, "T13", tokens{OAuth: []string{"U1"}}); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(mocked.Puts) != 1 || len(mocked.Deletes) != 0 {
+		t.Fatalf("Want the installation to be saved, got puts %+v, deletes %+v", mocked.Puts, mocked.Deletes)
+	}
+	var saved installation.Installation
+	if err := dynamo.UnmarshalItem(mocked.Puts[0].Item, &saved); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if saved.BotToken != "xoxb-1" || saved.UserToken != "" || saved.UserScope != "" {
+		t.Fatalf("Want only the user token to be dropped, got %+v", saved)
+	}
+}
+
+func TestPause(t *testing.T) {
+	mocked := &lifecycleDynamo{Settings: teamSettings("T14")}
+	db := dynamo.NewFromIface(mocked)
+
+	if err := pause(db, "C1", true); err != nil {
+		t.Fatalf("%q", err)
+	}
+	// Not a target channel
+	if err := pause(db, "C9", true); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if got := mocked.updated("paused"); !reflect.DeepEqual(got, []string{"C1"}) {
+		t.Fatalf("Want C1 to be paused, got %v", got)
+	}
+	if v := mocked.Updates[0].ExpressionAttributeValues; len(v) != 1 || !aws.BoolValue(firstValue(v).BOOL) {
+		t.Fatalf("Want paused to be set, got %+v", v)
+	}
+}
+
+func firstValue(values map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	for _, v := range values {
+		return v
+	}
+
+	return nil
+}
+
+func TestMemberJoined(t *testing.T) {
+	settings := teamSettings("T15")
+	settings[0].MembersFromChannel = true
+	mocked := &lifecycleDynamo{Settings: settings}
+	db := dynamo.NewFromIface(mocked)
+
+	cl := &slackapi.Fake{
+		Identity: slackapi.Identity{TeamID: "T15", UserID: "UBOT"},
+		Users: map[string]slackapi.User{
+			"U1":   {ID: "U1"},
+			"B1":   {ID: "B1", IsBot: true},
+			"UBOT": {ID: "UBOT"},
+		},
+	}
+
+	for _, join := range []struct{ channelID, userID string }{
+		{"C1", "U1"},
+		{"C1", "B1"},
+		{"C1", "UBOT"},
+		// Members listed by hand
+		{"C2", "U1"},
+	} {
+		if err := memberJoined(context.Background(), db, cl, "T15", join.channelID, join.userID); err != nil {
+			t.Fatalf("%q", err)
+		}
+	}
+
+	if got := mocked.updated("ADD"); !reflect.DeepEqual(got, []string{"C1"}) {
+		t.Fatalf("Want U1 to be added to C1 only, got %v", got)
+	}
+}
+
+func TestMemberLeft(t *testing.T) {
+	settings := teamSettings("T16")
+	settings[0].MembersFromChannel = true
+	mocked := &lifecycleDynamo{Settings: settings}
+	db := dynamo.NewFromIface(mocked)
+
+	if err := memberLeft(db, "C1", "U1"); err != nil {
+		t.Fatalf("%q", err)
+	}
+	if err := memberLeft(db, "C2", "U1"); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if got := mocked.updated("DELETE"); !reflect.DeepEqual(got, []string{"C1"}) {
+		t.Fatalf("Want U1 to be removed from C1 only, got %v", got)
+	}
+}
@@ -0,0 +1,511 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/installation"
+	"github.com/tsub/serverless-daily-standup-bot/internal/job"
+	"github.com/tsub/serverless-daily-standup-bot/internal/mrkdwn"
+	"github.com/tsub/serverless-daily-standup-bot/internal/slackapi"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+	"github.com/tsub/serverless-daily-standup-bot/internal/usercache"
+)
+
+// JobType is the job processing an event callback.
+const JobType = "webhook.event"
+
+// gracePeriod is how long after the end of its day a standup still takes
+// answers.
+var gracePeriod = 12 * time.Hour
+
+// attachWindow is how long after an answer files shared on their own still
+// go with it.
+const attachWindow = time.Minute
+
+type envelope struct {
+	APIAppID    string   `json:"api_app_id"`
+	AuthedUsers []string `json:"authed_users"`
+	Challenge   string   `json:"challenge"`
+	Event       event    `json:"event"`
+	EventID     string   `json:"event_id"`
+	EventTime   int      `json:"event_time"`
+	TeamID      string   `json:"team_id"`
+	Token       string   `json:"token"`
+	Type        string   `json:"type"`
+}
+
+type event struct {
+	Channel         channel `json:"channel"`
+	ChannelType     string  `json:"channel_type"`
+	ClientMessageID string  `json:"client_msg_id"`
+	DeletedTS       string  `json:"deleted_ts"`
+	Files           []file  `json:"files"`
+	EventTimestamp  string  `json:"event_ts"`
+	Hidden          bool    `json:"hidden"`
+	Message         message `json:"message"`
+	PreviousMessage message `json:"previous_message"`
+	Subtype         string  `json:"subtype"`
+	Text            string  `json:"text"`
+	ThreadTimestamp string  `json:"thread_ts"`
+	Timestamp       string  `json:"ts"`
+	Tokens          tokens  `json:"tokens"`
+	Type            string  `json:"type"`
+	User            user    `json:"user"`
+}
+
+// userID returns the author of a message, including an edited or deleted
+// one, or the user of another event.
+func (e event) userID() string {
+	switch e.Subtype {
+	case "message_changed":
+		return e.Message.User
+	case "message_deleted":
+		return e.PreviousMessage.User
+	}
+
+	return e.User.ID
+}
+
+// user is the "user" of an event, which is an ID for messages and the whole
+// user object for user_change.
+type user struct {
+	ID     string
+	Object *slackapi.User
+}
+
+func (u *user) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &u.ID); err == nil {
+		return nil
+	}
+
+	u.Object = &slackapi.User{}
+	if err := json.Unmarshal(b, u.Object); err != nil {
+		return err
+	}
+	u.ID = u.Object.ID
+
+	return nil
+}
+
+func (u user) MarshalJSON() ([]byte, error) {
+	if u.Object != nil {
+		return json.Marshal(u.Object)
+	}
+
+	return json.Marshal(u.ID)
+}
+
+type message struct {
+	ClientMessageID string `json:"client_msg_id"`
+	Edited          edited `json:"edited"`
+	Files           []file `json:"files"`
+	SourceTeam      string `json:"source_team"`
+	Team            string `json:"team"`
+	Text            string `json:"text"`
+	Timestamp       string `json:"ts"`
+	Type            string `json:"type"`
+	User            string `json:"user"`
+	UserTeam        string `json:"user_team"`
+}
+
+type file struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Title     string `json:"title"`
+	Permalink string `json:"permalink"`
+}
+
+// answerFiles returns the files of a message as stored with an answer.
+func answerFiles(files []file) []standup.File {
+	var answerFiles []standup.File
+	for _, f := range files {
+		name := f.Title
+		if name == "" {
+			name = f.Name
+		}
+		answerFiles = append(answerFiles, standup.File{ID: f.ID, Name: name, Permalink: f.Permalink})
+	}
+
+	return answerFiles
+}
+
+type edited struct {
+	Timestamp string `json:"ts"`
+	User      string `json:"user"`
+}
+
+func init() {
+	if v, err := time.ParseDuration(os.Getenv("ANSWER_GRACE_PERIOD")); err == nil && v >= 0 {
+		gracePeriod = v
+	}
+
+	job.Register(JobType, func(ctx context.Context, payload json.RawMessage) error {
+		var envelope envelope
+		if err := json.Unmarshal(payload, &envelope); err != nil {
+			return err
+		}
+
+		return process(ctx, envelope)
+	})
+}
+
+// Accept answers an Events API request right away and leaves the event to
+// a worker, returning the status code and plain text body of the response.
+func Accept(ctx context.Context, q job.Queue, body []byte) (int, string, error) {
+	var envelope envelope
+
+	log.Printf("raw request body: %s", body)
+
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return 400, "", err
+	}
+
+	switch envelope.Type {
+	case "url_verification":
+		return 200, envelope.Challenge, nil
+	case "event_callback":
+		if !handledEvents[envelope.Event.Type] {
+			return 200, "", nil
+		}
+
+		j, err := job.New(JobType, envelope)
+		if err != nil {
+			return 500, "", err
+		}
+		j.Key = job.UserKey(envelope.TeamID, envelope.Event.userID())
+
+		if err := q.Enqueue(ctx, j); err != nil {
+			return 500, "", err
+		}
+
+		return 200, "", nil
+	default:
+		return 200, "", nil
+	}
+}
+
+// Retried reports whether a request is Slack resending an event because
+// the response to the first one took over 3 seconds. That one has been
+// queued already, while retries after an error still have to be.
+// see https://api.slack.com/apis/connections/events-api#retries
+func Retried(retryNum string, retryReason string) bool {
+	return retryNum != "" && retryReason == "http_timeout"
+}
+
+func process(ctx context.Context, envelope envelope) error {
+	jsonEnvelope, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	log.Printf("envelope: %s", jsonEnvelope)
+
+	db := dynamo.New(session.New())
+
+	switch envelope.Event.Type {
+	case "message":
+		return processMessage(ctx, db, envelope)
+	case "user_change":
+		if envelope.Event.User.Object == nil {
+			return nil
+		}
+
+		return usercache.Refresh(db, *envelope.Event.User.Object)
+	case "app_uninstalled":
+		return uninstall(ctx, db, envelope.TeamID)
+	case "tokens_revoked":
+		return revokeTokens(ctx, db, envelope.TeamID, envelope.Event.Tokens)
+	case "channel_archive", "group_archive", "channel_deleted", "group_deleted":
+		return pause(db, envelope.Event.Channel.ID, true)
+	case "channel_unarchive", "group_unarchive":
+		return pause(db, envelope.Event.Channel.ID, false)
+	case "channel_rename", "group_rename":
+		log.Printf("channel %s renamed to %s", envelope.Event.Channel.ID, envelope.Event.Channel.Name)
+		return nil
+	case "member_joined_channel":
+		botcl, _, err := installation.Clients(db, envelope.TeamID)
+		if err != nil {
+			return err
+		}
+
+		return memberJoined(ctx, db, botcl, envelope.TeamID, envelope.Event.Channel.ID, envelope.Event.User.ID)
+	case "member_left_channel":
+		return memberLeft(db, envelope.Event.Channel.ID, envelope.Event.User.ID)
+	default:
+		return nil
+	}
+}
+
+func processMessage(ctx context.Context, db *dynamo.DB, envelope envelope) error {
+	botcl, cl, err := installation.Clients(db, envelope.TeamID)
+	if err != nil {
+		return err
+	}
+
+	authTestResp, err := usercache.GetIdentity(ctx, db, botcl, envelope.TeamID)
+	if err != nil {
+		return err
+	}
+
+	user := envelope.Event.userID()
+	var answer standup.Answer
+
+	switch envelope.Event.Subtype {
+	case "message_changed":
+		answer = standup.Answer{
+			Text:     mrkdwn.Normalize(envelope.Event.Message.Text),
+			Raw:      envelope.Event.Message.Text,
+			PostedAt: envelope.Event.Message.Timestamp,
+			EditedAt: envelope.Event.Message.Edited.Timestamp,
+			Files:    answerFiles(envelope.Event.Message.Files),
+		}
+	case "message_deleted":
+		answer = standup.Answer{PostedAt: envelope.Event.DeletedTS}
+	case "", "file_share": // new message
+		answer = standup.Answer{
+			Text:     mrkdwn.Normalize(envelope.Event.Text),
+			Raw:      envelope.Event.Text,
+			PostedAt: envelope.Event.Timestamp,
+			Files:    answerFiles(envelope.Event.Files),
+		}
+	default:
+		// unsupported subtype
+		// see https://api.slack.com/events/message#message_subtypes
+		log.Printf("unsupported subtype: %s", envelope.Event.Subtype)
+		return nil
+	}
+
+	// Skip self event
+	if user == authTestResp.UserID {
+		return nil
+	}
+
+	usersInfoResp, err := usercache.Get(ctx, db, cl, user)
+	if err != nil {
+		return err
+	}
+
+	// Look back further than today, an answer sent after midnight still
+	// belongs to yesterday's standup
+	since, err := standup.DateAt(usersInfoResp.TZ, time.Now().Add(-gracePeriod))
+	if err != nil {
+		return err
+	}
+
+	// Backfilled standups are open whatever their date
+	backfillSince, err := standup.DateAt(usersInfoResp.TZ, time.Now().AddDate(0, 0, -standup.BackfillDays))
+	if err != nil {
+		return err
+	}
+
+	standups, err := standup.Since(db, user, backfillSince, true)
+	if err != nil {
+		return err
+	}
+
+	var editedAt string
+	if envelope.Event.Subtype == "message_changed" || envelope.Event.Subtype == "message_deleted" {
+		editedAt = answer.PostedAt
+	}
+
+	if thread := envelope.Event.ThreadTimestamp; editedAt == "" && thread != "" && thread != answer.PostedAt {
+		return answerInThread(db, standups, thread, answer)
+	}
+
+	s := find(standups, since, editedAt)
+	if s == nil {
+		// Not a member of any open stand-up, nothing to retry
+		log.Printf("no stand-up for user: %s", user)
+		return nil
+	}
+
+	if editedAt != "" {
+		if s.Status != standup.StatusAsking && s.Status != standup.StatusCompleted {
+			return nil
+		}
+	} else {
+		if !s.Open() {
+			return nil
+		}
+
+		switch answer.Text {
+		case "cancel":
+			return closeStandup(ctx, db, botcl, s, s.Cancel, "Stand-up canceled.")
+		case "skip":
+			return closeStandup(ctx, db, botcl, s, s.Skip, "Stand-up skipped for today.")
+		case "same":
+			ok, err := sameAnswer(db, s, &answer)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				_, err := botcl.PostMessage(ctx, s.UserID, slackapi.Message{Text: "There is no previous answer to this question, please type it."})
+				return err
+			}
+		}
+	}
+
+	switch envelope.Event.Subtype {
+	case "message_deleted":
+		if err := s.DeleteAnswer(db, answer.PostedAt); err != nil {
+			return err
+		}
+	case "message_changed":
+		if err := s.UpdateAnswer(db, answer); err != nil {
+			return err
+		}
+	default:
+		if answer.Text == "" && len(answer.Files) > 0 && followsAnswer(s, answer.PostedAt) {
+			// Shared right after the answer it goes with
+			return s.AttachFiles(db, answer.Files)
+		}
+
+		if err := s.AppendAnswer(db, answer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// followsAnswer reports whether a message posted at a timestamp comes
+// within attachWindow of the latest answer of a standup. Files shared on
+// their own any later answer the question waiting instead.
+func followsAnswer(s *standup.Standup, postedAt string) bool {
+	index := s.NextQuestion()
+	if index == 0 {
+		return false
+	}
+
+	previous, err := strconv.ParseFloat(s.Answers[index-1].PostedAt, 64)
+	if err != nil {
+		return false
+	}
+	posted, err := strconv.ParseFloat(postedAt, 64)
+	if err != nil {
+		return false
+	}
+
+	return posted >= previous && posted-previous <= attachWindow.Seconds()
+}
+
+// answerInThread records a reply in the thread of a question as the answer
+// to that question, whichever question the standup is waiting for.
+func answerInThread(db *dynamo.DB, standups []standup.Standup, threadTS string, answer standup.Answer) error {
+	s, index := findQuestion(standups, threadTS)
+	if s == nil {
+		log.Printf("no question posted at %s", threadTS)
+		return nil
+	}
+
+	if s.Status != standup.StatusAsking && s.Status != standup.StatusCompleted {
+		return nil
+	}
+
+	return s.AnswerAt(db, index, answer)
+}
+
+// findQuestion returns the standup with the question posted at a timestamp
+// and the index of the question.
+func findQuestion(standups []standup.Standup, postedAt string) (*standup.Standup, int) {
+	for i := range standups {
+		if index := standups[i].Question(postedAt); index >= 0 {
+			return &standups[i], index
+		}
+	}
+
+	return nil, -1
+}
+
+// closeStandup ends a standup on the member's request and confirms it.
+func closeStandup(ctx context.Context, db *dynamo.DB, cl slackapi.Client, s *standup.Standup, close func(*dynamo.DB) error, text string) error {
+	if err := close(db); err != nil {
+		return err
+	}
+
+	if _, err := cl.PostMessage(ctx, s.UserID, slackapi.Message{Text: text}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// sameAnswer replaces an answer with the previous answer to the question a
+// standup is waiting for, and reports whether there was one.
+func sameAnswer(db *dynamo.DB, s *standup.Standup, answer *standup.Answer) (bool, error) {
+	index := s.NextQuestion()
+	if index >= len(s.Questions) {
+		return false, nil
+	}
+
+	previous, err := s.Previous(db)
+	if err != nil {
+		return false, err
+	}
+
+	text := standup.LastAnswerTo(previous, s.Questions[index].Text)
+	if text == "" {
+		return false, nil
+	}
+
+	// Raw would keep "same" otherwise
+	answer.Text = text
+	answer.Raw = text
+
+	return true, nil
+}
+
+// find returns the standup an answer goes to among standups sorted from the
+// most recent: the one with the edited answer, or among the open ones from
+// since on or backfilled, the one whose waiting question was sent last, so
+// that a backfill can run alongside the standup of today.
+func find(standups []standup.Standup, since string, editedAt string) *standup.Standup {
+	var found *standup.Standup
+	for i := range standups {
+		s := &standups[i]
+
+		if editedAt == "" {
+			if s.Open() && (s.Date >= since || s.Late) && (found == nil || askedAfter(s, found)) {
+				found = s
+			}
+			continue
+		}
+
+		if s.HasAnswer(editedAt) {
+			return s
+		}
+	}
+
+	return found
+}
+
+// askedAfter reports whether the question standup a waits for was sent
+// after the one of b.
+func askedAfter(a *standup.Standup, b *standup.Standup) bool {
+	ta, err := strconv.ParseFloat(waitingQuestion(a), 64)
+	if err != nil {
+		return false
+	}
+	tb, err := strconv.ParseFloat(waitingQuestion(b), 64)
+	if err != nil {
+		return true
+	}
+
+	return ta > tb
+}
+
+// waitingQuestion returns when the question a standup waits for was sent,
+// or "".
+func waitingQuestion(s *standup.Standup) string {
+	if index := s.NextQuestion(); index < len(s.Questions) {
+		return s.Questions[index].PostedAt
+	}
+
+	return ""
+}
@@ -0,0 +1,279 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/guregu/dynamo"
+	"github.com/tsub/serverless-daily-standup-bot/internal/job"
+	"github.com/tsub/serverless-daily-standup-bot/internal/standup"
+)
+
+// mockedDynamo serves the standups before the one answered.
+type mockedDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	Previous []standup.Standup
+}
+
+func (m *mockedDynamo) QueryWithContext(context aws.Context, input *dynamodb.QueryInput, options ...request.Option) (*dynamodb.QueryOutput, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, s := range m.Previous {
+		item, err := dynamo.MarshalItem(s)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return &dynamodb.QueryOutput{Items: items, Count: aws.Int64(int64(len(items)))}, nil
+}
+
+type recordingQueue struct {
+	Jobs []job.Job
+}
+
+func (q *recordingQueue) Enqueue(ctx context.Context, j job.Job) error {
+	q.Jobs = append(q.Jobs, j)
+	return nil
+}
+
+func TestAcceptURLVerification(t *testing.T) {
+	q := &recordingQueue{}
+
+	status, body, err := Accept(context.Background(), q, []byte(`{"type":"url_verification","challenge":"abc"}`))
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if status != 200 || body != "abc" {
+		t.Fatalf("Want 200 %q, got %d %q", "abc", status, body)
+	}
+}
+
+func TestAcceptEnqueuesMessage(t *testing.T) {
+	q := &recordingQueue{}
+
+	status, _, err := Accept(context.Background(), q, []byte(`{"type":"event_callback","team_id":"T1","event":{"type":"message","user":"U1","text":"hello","ts":"1.0"}}`))
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if status != 200 || len(q.Jobs) != 1 || q.Jobs[0].Type != JobType || q.Jobs[0].Key != "T1/U1" {
+		t.Fatalf("Unexpected status %d, jobs %+v", status, q.Jobs)
+	}
+}
+
+func TestAcceptKeysEditsByAuthor(t *testing.T) {
+	q := &recordingQueue{}
+
+	body := `{"type":"event_callback","team_id":"T1","event":{"type":"message","subtype":"message_changed","message":{"user":"U2","text":"hi","ts":"1.0"}}}`
+	if _, _, err := Accept(context.Background(), q, []byte(body)); err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(q.Jobs) != 1 || q.Jobs[0].Key != "T1/U2" {
+		t.Fatalf("Unexpected jobs %+v", q.Jobs)
+	}
+}
+
+func TestAcceptSkipsUnknownEvent(t *testing.T) {
+	q := &recordingQueue{}
+
+	status, _, err := Accept(context.Background(), q, []byte(`{"type":"event_callback","event":{"type":"reaction_added","user":"U1"}}`))
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if status != 200 || len(q.Jobs) != 0 {
+		t.Fatalf("Unexpected status %d, jobs %+v", status, q.Jobs)
+	}
+}
+
+func TestUserUnmarshalObject(t *testing.T) {
+	q := &recordingQueue{}
+
+	_, _, err := Accept(context.Background(), q, []byte(`{"type":"event_callback","event":{"type":"user_change","user":{"id":"U1","tz":"Asia/Tokyo"}}}`))
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if len(q.Jobs) != 1 {
+		t.Fatalf("Want 1 job, got %d", len(q.Jobs))
+	}
+}
+
+func TestFindOpenStandupAfterMidnight(t *testing.T) {
+	standups := []standup.Standup{
+		standup.Standup{Date: "2018-09-04", Status: standup.StatusCompleted},
+		standup.Standup{Date: "2018-09-03", Status: standup.StatusAsking},
+	}
+
+	s := find(standups, "2018-09-03", "")
+	if s == nil || s.Date != "2018-09-03" {
+		t.Fatalf("Want the open standup of 2018-09-03, got %+v", s)
+	}
+
+	if s := find(standups, "2018-09-04", ""); s != nil {
+		t.Fatalf("Want no standup past the grace period, got %+v", s)
+	}
+}
+
+func TestFindBackfilledStandup(t *testing.T) {
+	standups := []standup.Standup{
+		standup.Standup{Date: "2018-09-04", Status: standup.StatusCompleted},
+		standup.Standup{Date: "2018-09-01", Status: standup.StatusAsking, Late: true},
+	}
+
+	s := find(standups, "2018-09-04", "")
+	if s == nil || s.Date != "2018-09-01" {
+		t.Fatalf("Want the backfilled standup, got %+v", s)
+	}
+}
+
+func TestFindEditedAnswer(t *testing.T) {
+	standups := []standup.Standup{
+		standup.Standup{Date: "2018-09-04", Status: standup.StatusAsking},
+		standup.Standup{
+			Date:    "2018-09-03",
+			Status:  standup.StatusCompleted,
+			Answers: []standup.Answer{standup.Answer{Text: "a1", PostedAt: "1.0"}},
+		},
+	}
+
+	s := find(standups, "2018-09-03", "1.0")
+	if s == nil || s.Date != "2018-09-03" {
+		t.Fatalf("Want the standup of 2018-09-03, got %+v", s)
+	}
+
+	if s := find(standups, "2018-09-03", "2.0"); s != nil {
+		t.Fatalf("Want no standup, got %+v", s)
+	}
+}
+
+func TestFindQuestion(t *testing.T) {
+	standups := []standup.Standup{
+		standup.Standup{Date: "2018-09-04", Questions: []standup.Question{standup.Question{Text: "q1", PostedAt: "3.0"}}},
+		standup.Standup{
+			Date:      "2018-09-03",
+			Questions: []standup.Question{standup.Question{Text: "q1", PostedAt: "1.0"}, standup.Question{Text: "q2", PostedAt: "2.0"}},
+		},
+	}
+
+	s, index := findQuestion(standups, "2.0")
+	if s == nil || s.Date != "2018-09-03" || index != 1 {
+		t.Fatalf("Want the second question of 2018-09-03, got %+v %d", s, index)
+	}
+
+	if s, _ := findQuestion(standups, "9.0"); s != nil {
+		t.Fatalf("Want no standup, got %+v", s)
+	}
+}
+
+func TestRetried(t *testing.T) {
+	if !Retried("1", "http_timeout") {
+		t.Fatal("Want a retry after a timeout to be skipped")
+	}
+	if Retried("1", "http_error") || Retried("", "") {
+		t.Fatal("Want other requests to be handled")
+	}
+}
+
+func TestFollowsAnswer(t *testing.T) {
+	s := &standup.Standup{
+		Questions: []standup.Question{standup.Question{Text: "q1", PostedAt: "1.0"}, standup.Question{Text: "q2", PostedAt: "101.0"}},
+		Answers:   []standup.Answer{standup.Answer{Text: "a1", PostedAt: "100.0"}},
+	}
+
+	if !followsAnswer(s, "130.0") {
+		t.Fatal("Want files shared right after the answer to go with it")
+	}
+
+	// The second question waits for an answer
+	if followsAnswer(s, "400.0") {
+		t.Fatal("Want files shared later to answer the waiting question")
+	}
+
+	if followsAnswer(&standup.Standup{Questions: s.Questions}, "130.0") {
+		t.Fatal("Want files shared before any answer to answer the first question")
+	}
+}
+
+func TestFindBackfillAlongsideToday(t *testing.T) {
+	standups := []standup.Standup{
+		standup.Standup{
+			Date:      "2018-09-04",
+			Status:    standup.StatusAsking,
+			Questions: []standup.Question{standup.Question{Text: "q1", PostedAt: "1.0"}},
+		},
+		standup.Standup{
+			Date:      "2018-09-01",
+			Status:    standup.StatusAsking,
+			Late:      true,
+			Questions: []standup.Question{standup.Question{Text: "q1", PostedAt: "2.0"}},
+		},
+	}
+
+	// The question of the backfill was sent last
+	s := find(standups, "2018-09-04", "")
+	if s == nil || s.Date != "2018-09-01" {
+		t.Fatalf("Want the backfilled standup, got %+v", s)
+	}
+
+	standups[1].Answers = []standup.Answer{standup.Answer{Text: "a1", PostedAt: "2.5"}}
+	standups[1].Status = standup.StatusCompleted
+	s = find(standups, "2018-09-04", "")
+	if s == nil || s.Date != "2018-09-04" {
+		t.Fatalf("Want the standup of today once the backfill is done, got %+v", s)
+	}
+}
+
+func TestSameAnswer(t *testing.T) {
+	db := dynamo.NewFromIface(&mockedDynamo{Previous: []standup.Standup{
+		standup.Standup{
+			Date:      "2018-09-03",
+			Questions: []standup.Question{standup.Question{Text: "q1"}, standup.Question{Text: "q2"}},
+			Answers:   []standup.Answer{standup.Answer{Text: "a1"}, standup.Answer{Text: "*a2*"}},
+		},
+	}})
+
+	s := &standup.Standup{
+		Date:      "2018-09-04",
+		Status:    standup.StatusAsking,
+		Questions: []standup.Question{standup.Question{Text: "q1", PostedAt: "1.0"}, standup.Question{Text: "q2", PostedAt: "2.0"}},
+		Answers:   []standup.Answer{standup.Answer{Text: "b1", PostedAt: "1.5"}},
+	}
+
+	answer := standup.Answer{Text: "same", Raw: "same", PostedAt: "2.5"}
+	ok, err := sameAnswer(db, s, &answer)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if !ok || answer.Text != "*a2*" || answer.Raw != "*a2*" || answer.PostedAt != "2.5" {
+		t.Fatalf("Want the previous answer to q2, got %v %+v", ok, answer)
+	}
+}
+
+func TestSameAnswerWithoutPrevious(t *testing.T) {
+	db := dynamo.NewFromIface(&mockedDynamo{})
+
+	s := &standup.Standup{
+		Date:      "2018-09-04",
+		Status:    standup.StatusAsking,
+		Questions: []standup.Question{standup.Question{Text: "q1", PostedAt: "1.0"}},
+	}
+
+	answer := standup.Answer{Text: "same", Raw: "same", PostedAt: "1.5"}
+	ok, err := sameAnswer(db, s, &answer)
+	if err != nil {
+		t.Fatalf("%q", err)
+	}
+
+	if ok || answer.Text != "same" {
+		t.Fatalf("Want no previous answer, got %+v", answer)
+	}
+}